@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"document-server/internal/api/models"
+	"strconv"
+	"time"
+)
+
+func auditQuery(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("audit")
+	var query models.AuditQueryDTO
+	fset.StringVar(&query.Actor, "actor", "", "only events of this login or system actor")
+	fset.StringVar(&query.Document, "document", "", "only events about this document ID or login")
+	fset.StringVar(&query.Action, "action", "", "only this action, e.g. document.download")
+	fset.StringVar(&query.From, "from", "", "only events at or after this RFC 3339 time")
+	fset.StringVar(&query.To, "to", "", "only events before this RFC 3339 time")
+	limit := fset.Int("limit", 100, "maximum number of events, newest first")
+	if _, err := parseFlags(fset, args, 0); err != nil {
+		return err
+	}
+	query.Limit = strconv.Itoa(*limit)
+
+	events, err := a.audit.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(events)
+	}
+
+	rows := [][]string{{"TIME", "ACTION", "OUTCOME", "ACTOR", "TARGET", "IP", "DETAIL"}}
+	for _, e := range events {
+		rows = append(rows, []string{e.Time.Format(time.RFC3339), e.Action, e.Outcome, e.Actor, e.Target, e.IP, e.Detail})
+	}
+	return printTable(rows)
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"document-server/internal/service"
+	documentStorage "document-server/internal/storage/document"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type documentView struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Mime      string          `json:"mime"`
+	File      bool            `json:"file"`
+	Public    bool            `json:"public"`
+	Path      string          `json:"path,omitempty"`
+	Filename  string          `json:"filename,omitempty"`
+	SHA256    string          `json:"sha256,omitempty"`
+	MD5       string          `json:"md5,omitempty"`
+	Size      int64           `json:"size,omitempty"`
+	Stored    int64           `json:"stored_size,omitempty"`
+	Encoding  string          `json:"encoding,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	LegalHold bool            `json:"legal_hold"`
+	DeletedAt *time.Time      `json:"deleted_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	Grant     []string        `json:"grant"`
+	JSON      json.RawMessage `json:"json,omitempty"`
+}
+
+func toDocumentView(doc *documentStorage.Document) documentView {
+	v := documentView{
+		ID:        doc.ID.String(),
+		Name:      doc.Name,
+		Mime:      doc.MimeType,
+		File:      doc.IsFile,
+		Public:    doc.IsPublic,
+		Path:      doc.FilePath.String,
+		Filename:  doc.OriginalName.String,
+		SHA256:    doc.SHA256.String,
+		MD5:       doc.MD5.String,
+		Size:      doc.Size.Int64,
+		Stored:    doc.StoredSize.Int64,
+		Encoding:  doc.ContentEncoding.String,
+		LegalHold: doc.LegalHold,
+		CreatedAt: doc.CreatedAt,
+		Grant:     doc.GrantedTo,
+	}
+	if doc.JSONData.Valid {
+		v.JSON = json.RawMessage(doc.JSONData.String)
+	}
+	if doc.ExpiresAt.Valid {
+		v.ExpiresAt = &doc.ExpiresAt.Time
+	}
+	if doc.DeletedAt.Valid {
+		v.DeletedAt = &doc.DeletedAt.Time
+	}
+	return v
+}
+
+func docList(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("doc list")
+	login := fset.String("login", "", "only documents granted to this login")
+	limit := fset.Int("limit", 100, "maximum number of documents, 0 for all")
+	if _, err := parseFlags(fset, args, 0); err != nil {
+		return err
+	}
+
+	docs, err := a.docs.AdminListDocuments(ctx, *login, *limit)
+	if err != nil {
+		return err
+	}
+
+	views := make([]documentView, 0, len(docs))
+	for i := range docs {
+		v := toDocumentView(&docs[i])
+		v.JSON = nil
+		views = append(views, v)
+	}
+	if *asJSON {
+		return printJSON(views)
+	}
+
+	rows := [][]string{{"ID", "NAME", "MIME", "FILE", "PUBLIC", "CREATED", "GRANT"}}
+	for _, v := range views {
+		rows = append(rows, []string{v.ID, v.Name, v.Mime, strconv.FormatBool(v.File), strconv.FormatBool(v.Public), v.CreatedAt.Format(time.RFC3339), strings.Join(v.Grant, ",")})
+	}
+	return printTable(rows)
+}
+
+func docShow(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("doc show")
+	rest, err := parseFlags(fset, args, 1)
+	if err != nil {
+		return err
+	}
+
+	doc, data, err := a.docs.AdminGetDocument(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+
+	v := toDocumentView(doc)
+	if *asJSON {
+		return printJSON(v)
+	}
+
+	rows := [][]string{
+		{"id:", v.ID},
+		{"name:", v.Name},
+		{"mime:", v.Mime},
+		{"file:", strconv.FormatBool(v.File)},
+		{"public:", strconv.FormatBool(v.Public)},
+		{"created:", v.CreatedAt.Format(time.RFC3339)},
+		{"grant:", strings.Join(v.Grant, ",")},
+		{"legal hold:", strconv.FormatBool(v.LegalHold)},
+	}
+	if v.ExpiresAt != nil {
+		rows = append(rows, []string{"expires:", v.ExpiresAt.Format(time.RFC3339)})
+	}
+	if v.DeletedAt != nil {
+		rows = append(rows, []string{"in trash since:", v.DeletedAt.Format(time.RFC3339)})
+	}
+	if v.File {
+		rows = append(rows, []string{"filename:", v.Filename}, []string{"path:", v.Path}, []string{"size:", strconv.Itoa(len(data))}, []string{"sha256:", v.SHA256})
+		if v.MD5 != "" {
+			rows = append(rows, []string{"md5:", v.MD5})
+		}
+		if v.Stored > 0 {
+			rows = append(rows, []string{"stored size:", strconv.FormatInt(v.Stored, 10)})
+		}
+		if v.Encoding != "" {
+			rows = append(rows, []string{"encoding:", v.Encoding})
+		}
+	}
+	return printTable(rows)
+}
+
+// docExport пишет содержимое документа (файл или JSON) в файл или stdout.
+func docExport(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("doc export")
+	out := fset.String("o", "", "output file, stdout when empty")
+	rest, err := parseFlags(fset, args, 1)
+	if err != nil {
+		return err
+	}
+
+	doc, data, err := a.docs.AdminGetDocument(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+	if !doc.IsFile {
+		data = []byte(doc.JSONData.String)
+	}
+
+	if *out == "" {
+		if *asJSON {
+			return errUsage
+		}
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(map[string]any{"id": doc.ID.String(), "path": *out, "bytes": len(data)})
+	}
+	fmt.Printf("exported %s to %s (%d bytes)\n", doc.ID, *out, len(data))
+	return nil
+}
+
+func docDelete(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("doc delete")
+	rest, err := parseFlags(fset, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err := a.docs.AdminDeleteDocument(ctx, rest[0]); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(map[string]string{"deleted": rest[0]})
+	}
+	fmt.Printf("deleted document %s\n", rest[0])
+	return nil
+}
+
+func docRestore(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("doc restore")
+	rest, err := parseFlags(fset, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err := a.docs.AdminRestoreDocument(ctx, rest[0]); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(map[string]string{"restored": rest[0]})
+	}
+	fmt.Printf("restored document %s from trash\n", rest[0])
+	return nil
+}
+
+func docHold(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("doc hold")
+	release := fset.Bool("clear", false, "release the legal hold instead of placing it")
+	rest, err := parseFlags(fset, args, 1)
+	if err != nil {
+		return err
+	}
+
+	doc, err := a.docs.SetLegalHold(ctx, rest[0], !*release)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(toDocumentView(doc))
+	}
+	if doc.LegalHold {
+		fmt.Printf("placed legal hold on document %s\n", rest[0])
+	} else {
+		fmt.Printf("released legal hold on document %s\n", rest[0])
+	}
+	return nil
+}
+
+// expire выполняет один проход очистки: истекшие документы и старая корзина.
+func expire(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("expire")
+	trashDays := fset.Int("trash-days", a.cfg.Retention.TrashDays, "purge documents that spent more days than this in the trash, 0 to keep them")
+	if _, err := parseFlags(fset, args, 0); err != nil {
+		return err
+	}
+
+	deleted, err := a.docs.DeleteExpired(ctx, a.cfg.Retention.BatchSize)
+	if err != nil {
+		return err
+	}
+	var purged int
+	if *trashDays > 0 {
+		purged, err = a.docs.PurgeTrash(ctx, time.Duration(*trashDays)*24*time.Hour, a.cfg.Retention.BatchSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		return printJSON(map[string]int{"deleted": deleted, "purged": purged})
+	}
+	fmt.Printf("deleted %d expired document(s), purged %d from trash\n", deleted, purged)
+	return nil
+}
+
+func reconcile(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("reconcile")
+	action := fset.String("action", a.cfg.Reconciler.Action, "what to do with orphaned files: report, quarantine or delete")
+	grace := fset.Duration("grace", time.Duration(a.cfg.Reconciler.GracePeriod)*time.Minute, "skip files modified more recently than this")
+	if _, err := parseFlags(fset, args, 0); err != nil {
+		return err
+	}
+	return runReconcile(ctx, a, *action, *grace, *asJSON)
+}
+
+// collectGarbage - сокращение для reconcile -action delete.
+func collectGarbage(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("gc")
+	grace := fset.Duration("grace", time.Duration(a.cfg.Reconciler.GracePeriod)*time.Minute, "skip files modified more recently than this")
+	// min-age - прежнее имя -grace, оставлено для существующих скриптов.
+	fset.DurationVar(grace, "min-age", *grace, "alias for -grace")
+	dryRun := fset.Bool("dry-run", false, "only list orphaned files")
+	if _, err := parseFlags(fset, args, 0); err != nil {
+		return err
+	}
+
+	action := service.ReconcileDelete
+	if *dryRun {
+		action = service.ReconcileReport
+	}
+	return runReconcile(ctx, a, action, *grace, *asJSON)
+}
+
+func runReconcile(ctx context.Context, a *app, action string, grace time.Duration, asJSON bool) error {
+	report, err := a.reconciler.Reconcile(ctx, action, grace)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(report)
+	}
+
+	if len(report.Orphans) > 0 {
+		rows := [][]string{{"ORPHANED FILE", "SIZE", "MODIFIED", "RESULT"}}
+		for _, o := range report.Orphans {
+			result := "kept"
+			switch {
+			case o.Removed:
+				result = "removed"
+			case o.QuarantinedTo != "":
+				result = "quarantined to " + o.QuarantinedTo
+			}
+			rows = append(rows, []string{o.Name, strconv.FormatInt(o.Size, 10), o.ModTime.Format(time.RFC3339), result})
+		}
+		if err := printTable(rows); err != nil {
+			return err
+		}
+	}
+	if len(report.Missing) > 0 {
+		rows := [][]string{{"DOCUMENT WITHOUT CONTENT", "NAME", "PATH"}}
+		for _, m := range report.Missing {
+			rows = append(rows, []string{m.ID, m.Name, m.Path})
+		}
+		if err := printTable(rows); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("scanned %d file(s) and %d row(s): %d orphaned, %d missing content, %d bytes freed\n",
+		report.ScannedFiles, report.ScannedRows, len(report.Orphans), len(report.Missing), report.FreedBytes)
+	return nil
+}
+
+func scrub(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("scrub")
+	backfill := fset.Bool("backfill", false, "store checksums and sizes for documents uploaded before they were recorded")
+	if _, err := parseFlags(fset, args, 0); err != nil {
+		return err
+	}
+
+	report, err := a.docs.Scrub(ctx, *backfill, a.cfg.Integrity.MD5)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else {
+		if len(report.Failed) > 0 {
+			rows := [][]string{{"DOCUMENT", "NAME", "EXPECTED", "ACTUAL"}}
+			for _, f := range report.Failed {
+				actual := f.Actual
+				if f.Error != "" {
+					actual = f.Error
+				}
+				rows = append(rows, []string{f.ID, f.Name, f.Expected, actual})
+			}
+			if err := printTable(rows); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("checked %d file(s): %d ok, %d failed, %d without checksum, %d backfilled, %d sizes backfilled\n",
+			report.Checked, report.OK, len(report.Failed), report.Unverified, report.Backfilled, report.SizesBackfilled)
+	}
+
+	if len(report.Failed) > 0 {
+		return errScrubFailed
+	}
+	return nil
+}
+
+func backfillSizes(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("backfill-sizes")
+	if _, err := parseFlags(fset, args, 0); err != nil {
+		return err
+	}
+
+	filled, failed, err := a.docs.BackfillSizes(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(map[string]int{"filled": filled, "failed": failed})
+	}
+	fmt.Printf("filled sizes of %d file(s), %d unreadable\n", filled, failed)
+	return nil
+}
+
+func rotateKeys(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("rotate-keys")
+	if _, err := parseFlags(fset, args, 0); err != nil {
+		return err
+	}
+	if !a.cfg.Encryption.Enabled {
+		return errors.New("encryption is disabled")
+	}
+
+	report, err := a.docs.RotateKeys(ctx, a.cfg.Encryption.KeyID)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else {
+		if len(report.Failed) > 0 {
+			rows := [][]string{{"DOCUMENT", "NAME", "KEY", "ERROR"}}
+			for _, f := range report.Failed {
+				rows = append(rows, []string{f.ID, f.Name, f.KeyID, f.Error})
+			}
+			if err := printTable(rows); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("checked %d file(s): %d rewrapped to %s, %d failed, %d not encrypted\n",
+			report.Checked, report.Rewrapped, report.ActiveKeyID, len(report.Failed), report.Plaintext)
+	}
+
+	if len(report.Failed) > 0 {
+		return errRotateFailed
+	}
+	return nil
+}
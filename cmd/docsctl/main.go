@@ -0,0 +1,181 @@
+// docsctl работает с базой и файлами сервера напрямую, без HTTP и admin токена.
+package main
+
+import (
+	"context"
+	"document-server/internal/cache"
+	"document-server/internal/config"
+	"document-server/internal/infrastructure/database/postgres"
+	"document-server/internal/service"
+	apikey "document-server/internal/storage/apikey"
+	audit "document-server/internal/storage/audit"
+	blob "document-server/internal/storage/blob"
+	document "document-server/internal/storage/document"
+	token "document-server/internal/storage/token"
+	user "document-server/internal/storage/user"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/hedhyw/semerr/pkg/v1/httperr"
+)
+
+const usage = `usage: docsctl [config flags] <command> [flags] [args]
+
+commands:
+  user create [-admin] [-password P] LOGIN
+  user list
+  user delete LOGIN
+  user quota [-bytes N|default] [-docs N|default] LOGIN
+  user group [-clear] LOGIN [GROUP]
+  group quota [-bytes N|default] [-docs N|default] NAME
+  token revoke TOKEN
+  token revoke -user LOGIN
+  doc list [-login L] [-limit N]
+  doc show ID
+  doc export [-o FILE] ID
+  doc delete ID
+  doc restore ID
+  doc hold [-clear] ID
+  expire [-trash-days N]
+  reconcile [-action report|quarantine|delete] [-grace 1h]
+  gc [-grace|-min-age 1h] [-dry-run]
+  scrub [-backfill]
+  backfill-sizes
+  rotate-keys
+  audit [-actor L] [-document ID] [-action A] [-from T] [-to T] [-limit N]
+
+every command accepts -json for machine-readable output.
+config flags are the same as the server's, e.g. -config, -database.host.`
+
+// app - собранные сервисы, с которыми работают команды.
+type app struct {
+	users      *service.UserService
+	quotas     *service.QuotaService
+	docs       *service.DocumentService
+	reconciler *service.Reconciler
+	audit      *service.AuditService
+	cfg        *config.Config
+}
+
+type command func(ctx context.Context, a *app, args []string) error
+
+var commands = map[string]command{
+	"user create":    userCreate,
+	"user list":      userList,
+	"user delete":    userDelete,
+	"user quota":     userQuota,
+	"user group":     userGroup,
+	"group quota":    groupQuota,
+	"token revoke":   tokenRevoke,
+	"doc list":       docList,
+	"doc show":       docShow,
+	"doc export":     docExport,
+	"doc delete":     docDelete,
+	"doc restore":    docRestore,
+	"doc hold":       docHold,
+	"expire":         expire,
+	"reconcile":      reconcile,
+	"gc":             collectGarbage,
+	"scrub":          scrub,
+	"backfill-sizes": backfillSizes,
+	"rotate-keys":    rotateKeys,
+	"audit":          auditQuery,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	cfg, rest, err := config.Load("docsctl", args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	cmd, cmdArgs, ok := lookupCommand(rest)
+	if !ok {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	db, err := postgres.Connect(&cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to connect to db: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	userStorage := user.NewUserStorage(db)
+	tokenStorage := token.NewTokenStorage(db)
+	authenticator := service.NewTokenAuthenticator(userStorage, tokenStorage, apikey.NewAPIKeyStorage(db), cfg.Auth, logger)
+
+	keyring, err := blob.LoadKeyring(cfg.Encryption)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load encryption keys: %v\n", err)
+		return 1
+	}
+	blobs := blob.NewFileStore(cfg.FileStorage.Path, blob.Options{MD5: cfg.Integrity.MD5, Keyring: keyring, CompressionLevel: cfg.Compression.Level})
+
+	docStorage := document.NewDocumentStorage(db)
+	auditStorage := audit.NewAuditStorage(db)
+	auditLog := service.NewAuditLog(auditStorage, logger)
+	users := service.NewUserService(userStorage, tokenStorage, auditLog, logger, cfg.AdminToken)
+	quotas := service.NewQuotaService(docStorage, userStorage, authenticator, users, cfg.Quota, logger)
+
+	// Удаления через docsctl должны убирать документ и из общего кэша серверов.
+	var docCache service.Cache = cache.NewInMemoryCache(cfg.CacheConfig)
+	if cfg.CacheConfig.Backend != "memory" {
+		sharedCache := cache.NewSharedCache(cfg.CacheConfig.Shared, logger)
+		defer sharedCache.Close()
+		docCache = sharedCache
+	}
+
+	a := &app{
+		users:  users,
+		quotas: quotas,
+		docs:   service.NewDocumentService(docStorage, authenticator, logger, blobs, service.NewMIMEPolicy(cfg.MIMEPolicy), service.NewCompressionPolicy(cfg.Compression), quotas, cfg.Integrity, cfg.Auth, auditLog, nil, docCache),
+		reconciler: service.NewReconciler(docStorage, users, service.ReconcilerOptions{
+			StoragePath:    cfg.FileStorage.Path,
+			QuarantinePath: cfg.Reconciler.QuarantinePath,
+		}, logger),
+		audit: service.NewAuditService(auditStorage, users, logger),
+		cfg:   cfg,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd(ctx, a, cmdArgs); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		if httperr.Code(err) < 500 {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// lookupCommand ищет команду из одного или двух слов.
+func lookupCommand(args []string) (command, []string, bool) {
+	if len(args) >= 2 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return cmd, args[2:], true
+		}
+	}
+	if len(args) >= 1 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd, args[1:], true
+		}
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+var (
+	errUsage        = errors.New("usage")
+	errScrubFailed  = errors.New("some files failed the integrity check")
+	errRotateFailed = errors.New("some keys could not be rewrapped")
+)
+
+// newFlagSet создает набор флагов команды с общим флагом -json.
+func newFlagSet(name string) (*flag.FlagSet, *bool) {
+	fset := flag.NewFlagSet(name, flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	asJSON := fset.Bool("json", false, "print JSON instead of a table")
+	return fset, asJSON
+}
+
+func parseFlags(fset *flag.FlagSet, args []string, positional int) ([]string, error) {
+	if err := fset.Parse(args); err != nil {
+		return nil, errUsage
+	}
+	if fset.NArg() != positional {
+		return nil, errUsage
+	}
+	return fset.Args(), nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printTable выводит строки, выровненные по колонкам; первая строка - заголовок.
+func printTable(rows [][]string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, cell)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"document-server/internal/api/models"
+	userStorage "document-server/internal/storage/user"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type userView struct {
+	ID        string    `json:"id"`
+	Login     string    `json:"login"`
+	Admin     bool      `json:"admin"`
+	External  bool      `json:"external"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toUserView(u userStorage.User) userView {
+	return userView{
+		ID:        u.ID.String(),
+		Login:     u.Login,
+		Admin:     u.IsAdmin,
+		External:  u.ExternalSubject.Valid,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+func userCreate(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("user create")
+	admin := fset.Bool("admin", false, "grant administrator rights")
+	password := fset.String("password", "", "password; read from stdin when empty")
+	rest, err := parseFlags(fset, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if *password == "" {
+		if *password, err = readPassword(); err != nil {
+			return err
+		}
+	}
+
+	created, err := a.users.CreateUser(ctx, rest[0], *password, *admin)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(toUserView(*created))
+	}
+	fmt.Printf("created user %s (%s), admin: %t\n", created.Login, created.ID, created.IsAdmin)
+	return nil
+}
+
+func userList(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("user list")
+	if _, err := parseFlags(fset, args, 0); err != nil {
+		return err
+	}
+
+	users, err := a.users.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	views := make([]userView, 0, len(users))
+	for _, u := range users {
+		views = append(views, toUserView(u))
+	}
+	if *asJSON {
+		return printJSON(views)
+	}
+
+	rows := [][]string{{"ID", "LOGIN", "ADMIN", "EXTERNAL", "CREATED"}}
+	for _, v := range views {
+		rows = append(rows, []string{v.ID, v.Login, strconv.FormatBool(v.Admin), strconv.FormatBool(v.External), v.CreatedAt.Format(time.RFC3339)})
+	}
+	return printTable(rows)
+}
+
+func userDelete(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("user delete")
+	rest, err := parseFlags(fset, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err := a.users.DeleteUser(ctx, rest[0]); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(map[string]string{"deleted": rest[0]})
+	}
+	fmt.Printf("deleted user %s\n", rest[0])
+	return nil
+}
+
+func tokenRevoke(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("token revoke")
+	login := fset.String("user", "", "revoke every session token of this user")
+	if err := fset.Parse(args); err != nil {
+		return errUsage
+	}
+
+	var revoked int64
+	switch {
+	case *login != "" && fset.NArg() == 0:
+		n, err := a.users.RevokeUserTokens(ctx, *login)
+		if err != nil {
+			return err
+		}
+		revoked = n
+	case *login == "" && fset.NArg() == 1:
+		if err := a.users.Logout(ctx, fset.Arg(0)); err != nil {
+			return err
+		}
+		revoked = 1
+	default:
+		return errUsage
+	}
+
+	if *asJSON {
+		return printJSON(map[string]int64{"revoked": revoked})
+	}
+	fmt.Printf("revoked %d token(s)\n", revoked)
+	return nil
+}
+
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("password is required")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// userQuota: пропущенный флаг не меняет квоту, default возвращает значение из конфигурации.
+func userQuota(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("user quota")
+	bytes := fset.String("bytes", "", "byte quota, 0 for unlimited, default for the config value")
+	docs := fset.String("docs", "", "document count quota, 0 for unlimited, default for the config value")
+	rest, err := parseFlags(fset, args, 1)
+	if err != nil {
+		return err
+	}
+
+	var usage *models.UsageDTO
+	if *bytes == "" && *docs == "" {
+		usage, err = a.quotas.UserUsage(ctx, rest[0])
+	} else {
+		usage, err = setQuota(ctx, a, rest[0], *bytes, *docs)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(usage)
+	}
+	return printTable([][]string{
+		{"login:", usage.Login},
+		{"bytes:", formatQuota(usage.Bytes, usage.MaxBytes)},
+		{"documents:", formatQuota(usage.Documents, usage.MaxDocuments)},
+	})
+}
+
+// userGroup переводит пользователя в группу GROUP, с -clear - убирает из группы.
+func userGroup(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("user group")
+	leave := fset.Bool("clear", false, "remove the user from their group")
+	if err := fset.Parse(args); err != nil {
+		return errUsage
+	}
+	if (*leave && fset.NArg() != 1) || (!*leave && fset.NArg() != 2) {
+		return errUsage
+	}
+
+	var group string
+	if !*leave {
+		group = fset.Arg(1)
+	}
+	usage, err := a.quotas.SetGroup(ctx, fset.Arg(0), group)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(usage)
+	}
+	if usage.Group == nil {
+		fmt.Printf("user %s is in no group\n", usage.Login)
+		return nil
+	}
+	fmt.Printf("user %s is in group %s\n", usage.Login, usage.Group.Name)
+	return nil
+}
+
+func groupQuota(ctx context.Context, a *app, args []string) error {
+	fset, asJSON := newFlagSet("group quota")
+	bytes := fset.String("bytes", "", "byte quota, 0 for unlimited, default for the config value")
+	docs := fset.String("docs", "", "document count quota, 0 for unlimited, default for the config value")
+	rest, err := parseFlags(fset, args, 1)
+	if err != nil {
+		return err
+	}
+
+	var usage *models.GroupUsageDTO
+	if *bytes == "" && *docs == "" {
+		usage, err = a.quotas.GroupUsage(ctx, rest[0])
+	} else {
+		var maxBytes, maxDocs *sql.NullInt64
+		if maxBytes, err = parseQuota(*bytes); err != nil {
+			return err
+		}
+		if maxDocs, err = parseQuota(*docs); err != nil {
+			return err
+		}
+		usage, err = a.quotas.SetGroupQuota(ctx, rest[0], maxBytes, maxDocs)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(usage)
+	}
+	return printTable([][]string{
+		{"group:", usage.Name},
+		{"bytes:", formatQuota(usage.Bytes, usage.MaxBytes)},
+		{"documents:", formatQuota(usage.Documents, usage.MaxDocuments)},
+	})
+}
+
+func setQuota(ctx context.Context, a *app, login, bytes, docs string) (*models.UsageDTO, error) {
+	maxBytes, err := parseQuota(bytes)
+	if err != nil {
+		return nil, err
+	}
+	maxDocs, err := parseQuota(docs)
+	if err != nil {
+		return nil, err
+	}
+	return a.quotas.SetQuota(ctx, login, maxBytes, maxDocs)
+}
+
+// parseQuota: пустое значение - не менять, default - значение из конфигурации.
+func parseQuota(raw string) (*sql.NullInt64, error) {
+	switch raw {
+	case "":
+		return nil, nil
+	case "default":
+		return &sql.NullInt64{}, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid quota %q", raw)
+	}
+	return &sql.NullInt64{Int64: n, Valid: true}, nil
+}
+
+func formatQuota(used, limit int64) string {
+	if limit == 0 {
+		return strconv.FormatInt(used, 10) + " (unlimited)"
+	}
+	return fmt.Sprintf("%d of %d", used, limit)
+}
@@ -4,14 +4,24 @@ import (
 	"context"
 	"document-server/internal/api"
 	"document-server/internal/api/controller"
+	"document-server/internal/api/middleware"
 	"document-server/internal/cache"
 	"document-server/internal/config"
 	"document-server/internal/infrastructure/database/postgres"
+	"document-server/internal/infrastructure/oidc"
 	"document-server/internal/logger"
+	"document-server/internal/metrics"
 	"document-server/internal/service"
+	apikey "document-server/internal/storage/apikey"
+	audit "document-server/internal/storage/audit"
+	blob "document-server/internal/storage/blob"
 	document "document-server/internal/storage/document"
+	schema "document-server/internal/storage/schema"
 	token "document-server/internal/storage/token"
 	user "document-server/internal/storage/user"
+	webhook "document-server/internal/storage/webhook"
+	"document-server/internal/tracing"
+	"document-server/migrations"
 
 	"log"
 	"log/slog"
@@ -23,19 +33,53 @@ import (
 )
 
 func main() {
-	cfg, err := config.LoadConfig("configs/config.json")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	loadConfig := func() (*config.Config, error) {
+		cfg, _, err := config.Load("server", os.Args[1:])
+		if err != nil {
+			return nil, err
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+
+	cfg, err := loadConfig()
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
-	logger := logger.New(cfg.Log.Level)
-	slog.SetDefault(logger)
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(logger.ParseLevel(cfg.Log.Level))
+	appLogger := logger.New(logLevel)
+	slog.SetDefault(appLogger)
 
-	logger.Info("configuration loaded", slog.String("addr", cfg.Server.Address))
+	appLogger.Info("configuration loaded", slog.String("addr", cfg.Server.Address))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("failed to set up tracing: %v", err)
+	}
 
 	if _, err := os.Stat(cfg.FileStorage.Path); os.IsNotExist(err) {
 		os.MkdirAll(cfg.FileStorage.Path, 0755)
-		logger.Info("created file storage directory", slog.String("path", cfg.FileStorage.Path))
+		appLogger.Info("created file storage directory", slog.String("path", cfg.FileStorage.Path))
+	}
+
+	if cfg.Database.AutoMigrate {
+		migrator, err := postgres.NewMigrator(&cfg.Database, appLogger)
+		if err != nil {
+			log.Fatalf("failed to create migrator: %v", err)
+		}
+		if err := migrator.Up(); err != nil {
+			log.Fatalf("failed to apply migrations: %v", err)
+		}
+		migrator.Close()
+		appLogger.Info("migrations applied")
 	}
 
 	db, err := postgres.Connect(&cfg.Database)
@@ -44,53 +88,229 @@ func main() {
 	}
 	defer db.Close()
 
-	logger.Info("Database connected")
+	appLogger.Info("Database connected")
+	metrics.RegisterDBStats(db, cfg.Database.Name)
 
 	docStorage := document.NewDocumentStorage(db)
 	userStorage := user.NewUserStorage(db)
 	tokenStorage := token.NewTokenStorage(db)
+	apiKeyStorage := apikey.NewAPIKeyStorage(db)
+	schemaStorage := schema.NewSchemaStorage(db)
+	auditStorage := audit.NewAuditStorage(db)
+	webhookStorage := webhook.NewWebhookStorage(db)
+
+	expectedVersion, err := schema.LatestMigrationVersion(migrations.FS)
+	if err != nil {
+		log.Fatalf("failed to read migrations: %v", err)
+	}
+
+	// Перед общим кэшем локальный уровень - LRU, сам по себе - кэш в памяти с TTL.
+	var localCache cache.Local
+	var lruCache *cache.LRUCache
+	if cfg.CacheConfig.Backend == "tiered" {
+		lruCache = cache.NewLRUCache(cfg.CacheConfig)
+		localCache = lruCache
+	} else {
+		localCache = cache.NewInMemoryCache(cfg.CacheConfig)
+	}
+	metrics.RegisterCache("memory", func() metrics.CacheStats {
+		stats := localCache.Stats()
+		return metrics.CacheStats{Hits: stats.Hits, Misses: stats.Misses, Evictions: stats.Evictions, Size: stats.Size}
+	})
+
+	var docCache service.Cache = localCache
+	var cacheStats service.CacheStatsProvider = localCache
+	if cfg.CacheConfig.Backend != "memory" {
+		sharedCache := cache.NewSharedCache(cfg.CacheConfig.Shared, appLogger)
+		defer sharedCache.Close()
+		if err := sharedCache.Ping(context.Background()); err != nil {
+			appLogger.Warn("shared cache is unreachable, serving from the database", slog.String("address", cfg.CacheConfig.Shared.Address), slog.String("error", err.Error()))
+		}
+		metrics.RegisterCache("shared", func() metrics.CacheStats {
+			stats := sharedCache.Stats()
+			return metrics.CacheStats{Hits: stats.Hits, Misses: stats.Misses, Errors: stats.Errors}
+		})
+
+		docCache, cacheStats = sharedCache, sharedCache
+		if cfg.CacheConfig.Backend == "tiered" {
+			tiered := cache.NewTieredCache(lruCache, sharedCache)
+			docCache, cacheStats = tiered, tiered
+		}
+	}
+
+	var authenticator service.Authenticator = service.NewTokenAuthenticator(userStorage, tokenStorage, apiKeyStorage, cfg.Auth, appLogger)
+	if cfg.OIDC.Enabled {
+		var keySet *oidc.KeySet
+		if cfg.OIDC.JWKSFile != "" {
+			keySet, err = oidc.NewFileKeySet(cfg.OIDC.JWKSFile)
+		} else {
+			keySet, err = oidc.NewRemoteKeySet(context.Background(), cfg.OIDC.JWKSURL, time.Duration(cfg.OIDC.JWKSRefreshInterval)*time.Second)
+		}
+		if err != nil {
+			log.Fatalf("failed to load JWKS: %v", err)
+		}
 
-	inMemoryCache := cache.NewInMemoryCache(cfg.CacheConfig)
+		jwtAuthenticator := service.NewJWTAuthenticator(keySet, userStorage, appLogger, service.JWTAuthenticatorOptions{
+			Issuer:        cfg.OIDC.Issuer,
+			Audience:      cfg.OIDC.Audience,
+			ClockSkew:     time.Duration(cfg.OIDC.ClockSkew) * time.Second,
+			LoginClaim:    cfg.OIDC.LoginClaim,
+			AutoProvision: cfg.OIDC.AutoProvision,
+		})
+		authenticator = service.NewCompositeAuthenticator(authenticator, jwtAuthenticator)
+		appLogger.Info("OIDC authentication enabled", slog.String("issuer", cfg.OIDC.Issuer))
+	}
 
-	docService := service.NewDocumentService(userStorage, docStorage, tokenStorage, logger, cfg.FileStorage.Path, inMemoryCache)
-	authService := service.NewUserService(userStorage, tokenStorage, logger, cfg.AdminToken)
+	keyring, err := blob.LoadKeyring(cfg.Encryption)
+	if err != nil {
+		log.Fatalf("failed to load encryption keys: %v", err)
+	}
+	blobs := blob.NewFileStore(cfg.FileStorage.Path, blob.Options{MD5: cfg.Integrity.MD5, Keyring: keyring, CompressionLevel: cfg.Compression.Level})
 
-	router, err := api.NewRouter()
+	auditLog := service.NewAuditLog(auditStorage, appLogger)
+	eventBus := service.NewEventBus(cfg.Events.History)
+	authService := service.NewUserService(userStorage, tokenStorage, auditLog, appLogger, cfg.AdminToken)
+	quotaService := service.NewQuotaService(docStorage, userStorage, authenticator, authService, cfg.Quota, appLogger)
+	docService := service.NewDocumentService(docStorage, authenticator, appLogger, blobs, service.NewMIMEPolicy(cfg.MIMEPolicy), service.NewCompressionPolicy(cfg.Compression), quotaService, cfg.Integrity, cfg.Auth, auditLog, eventBus, docCache)
+	apiKeyService := service.NewAPIKeyService(apiKeyStorage, authenticator, appLogger)
+	healthService := service.NewHealthService(schemaStorage, cacheStats, authService, cfg.FileStorage.Path, expectedVersion, time.Duration(cfg.Health.CheckTimeout)*time.Second)
+
+	router, err := api.NewRouter(appLogger)
 	if err != nil {
 		log.Fatalf("failed to create router: %v", err)
 	}
 
 	userController := controller.NewUserController(authService)
-	docsController := controller.NewDocumentController(docService)
+	docsController := controller.NewDocumentController(docService, cfg.Upload)
+	apiKeyController := controller.NewAPIKeyController(apiKeyService)
+	healthController := controller.NewHealthController(healthService)
+	quotaController := controller.NewQuotaController(quotaService)
+	auditController := controller.NewAuditController(service.NewAuditService(auditStorage, authService, appLogger))
+
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimit)
+	router.Use(rateLimiter.Middleware)
+
+	reloadService := service.NewReloadService(cfg, loadConfig, func(next *config.Config) {
+		logLevel.Set(logger.ParseLevel(next.Log.Level))
+		localCache.Configure(next.CacheConfig)
+		rateLimiter.Configure(next.RateLimit)
+		docsController.SetUploadLimits(next.Upload)
+	}, authService, appLogger)
+	configController := controller.NewConfigController(reloadService)
+
+	reconciler := service.NewReconciler(docStorage, authService, service.ReconcilerOptions{
+		StoragePath:    cfg.FileStorage.Path,
+		QuarantinePath: cfg.Reconciler.QuarantinePath,
+		GracePeriod:    time.Duration(cfg.Reconciler.GracePeriod) * time.Minute,
+		Action:         cfg.Reconciler.Action,
+		Interval:       time.Duration(cfg.Reconciler.Interval) * time.Minute,
+	}, appLogger)
+	reconcileController := controller.NewReconcileController(reconciler)
+
+	retention := service.NewRetention(docService, authService, webhookStorage, service.RetentionOptions{
+		Interval:           time.Duration(cfg.Retention.Interval) * time.Minute,
+		BatchSize:          cfg.Retention.BatchSize,
+		TrashPeriod:        time.Duration(cfg.Retention.TrashDays) * 24 * time.Hour,
+		EventPeriod:        time.Duration(cfg.Retention.EventDays) * 24 * time.Hour,
+		UndispatchedEvents: cfg.Webhooks.Interval == 0,
+	}, appLogger)
+	retentionController := controller.NewRetentionController(retention)
+	trashController := controller.NewTrashController(docService)
+	webhookController := controller.NewWebhookController(service.NewWebhookService(webhookStorage, authenticator, appLogger))
+	eventController := controller.NewEventController(docService, time.Duration(cfg.Events.Heartbeat)*time.Second)
 
 	router.SetUserRoutes(userController)
 	router.SetDocsRoutes(docsController)
+	router.SetAPIKeyRoutes(apiKeyController)
+	router.SetMetricsRoutes(metrics.Handler())
+	router.SetHealthRoutes(healthController)
+	router.SetAdminRoutes(configController)
+	router.SetReconcileRoutes(reconcileController)
+	router.SetQuotaRoutes(quotaController)
+	router.SetRetentionRoutes(retentionController)
+	router.SetTrashRoutes(trashController)
+	router.SetAuditRoutes(auditController)
+	router.SetWebhookRoutes(webhookController)
+	router.SetEventRoutes(eventController)
+
+	appCtx, stopApp := context.WithCancel(context.Background())
+	defer stopApp()
+
+	if cfg.TokenJanitor.Interval > 0 {
+		tokenJanitor := service.NewTokenJanitor(tokenStorage, time.Duration(cfg.TokenJanitor.Interval)*time.Minute, appLogger)
+		go tokenJanitor.Run(appCtx)
+	}
+
+	if cfg.Reconciler.Interval > 0 {
+		go reconciler.Run(appCtx)
+	}
+
+	if cfg.Retention.Interval > 0 {
+		go retention.Run(appCtx)
+	}
+
+	// Общий кэш один на все экземпляры, уведомления нужны только кэшу в памяти.
+	if cfg.CacheConfig.Listen && cfg.CacheConfig.Backend != "shared" {
+		invalidation := service.NewCacheInvalidation(localCache, time.Duration(cfg.CacheConfig.FallbackTTL)*time.Second, appLogger)
+		go postgres.NewListener(&cfg.Database, document.ChangesChannel, invalidation, appLogger).Run(appCtx)
+	}
+
+	if cfg.Webhooks.Interval > 0 {
+		dispatcher := service.NewWebhookDispatcher(webhookStorage, service.WebhookOptions{
+			Interval:     time.Duration(cfg.Webhooks.Interval) * time.Second,
+			BatchSize:    cfg.Webhooks.BatchSize,
+			Timeout:      time.Duration(cfg.Webhooks.Timeout) * time.Second,
+			MaxAttempts:  cfg.Webhooks.MaxAttempts,
+			BackoffBase:  time.Duration(cfg.Webhooks.BackoffBase) * time.Second,
+			BackoffMax:   time.Duration(cfg.Webhooks.BackoffMax) * time.Second,
+			AllowPrivate: cfg.Webhooks.AllowPrivate,
+		}, appLogger)
+		go dispatcher.Run(appCtx)
+	}
 
 	srv := &http.Server{
 		Addr:    cfg.Server.Address,
 		Handler: router,
 	}
+	// Shutdown не дожидается конца лент событий сам: их нужно закрыть.
+	srv.RegisterOnShutdown(eventBus.Close)
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		logger.Info("starting server", slog.String("addr", cfg.Server.Address))
+		for range hup {
+			appLogger.Info("SIGHUP received, reloading config")
+			reloadService.Reload(appCtx)
+		}
+	}()
+
+	go func() {
+		appLogger.Info("starting server", slog.String("addr", cfg.Server.Address))
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("server error", slog.String("error", err.Error()))
+			appLogger.Error("server error", slog.String("error", err.Error()))
 			os.Exit(1)
 		}
 	}()
 
 	<-stop
-	logger.Info("shutting down server...")
+	appLogger.Info("shutting down server...")
+	healthService.SetDraining()
+	time.Sleep(time.Duration(cfg.Health.DrainDelay) * time.Second)
+	stopApp()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		logger.Error("failed to shutdown gracefully", slog.String("error", err.Error()))
+		appLogger.Error("failed to shutdown gracefully", slog.String("error", err.Error()))
 	} else {
-		logger.Info("server stopped gracefully")
+		appLogger.Info("server stopped gracefully")
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		appLogger.Error("failed to flush traces", slog.String("error", err.Error()))
 	}
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"document-server/internal/config"
+	"document-server/internal/infrastructure/database/postgres"
+	"document-server/internal/logger"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = "usage: server migrate [flags] up | down [N] | status | force VERSION"
+
+// runMigrate обрабатывает подкоманду "server migrate".
+func runMigrate(args []string) int {
+	cfg, rest, err := config.Load("migrate", args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(rest) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(logger.ParseLevel(cfg.Log.Level))
+
+	migrator, err := postgres.NewMigrator(&cfg.Database, logger.New(logLevel))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer migrator.Close()
+
+	switch rest[0] {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		steps := 1
+		if len(rest) > 1 {
+			steps, err = strconv.Atoi(rest[1])
+			if err != nil || steps <= 0 {
+				fmt.Fprintln(os.Stderr, "down expects a positive number of steps")
+				return 2
+			}
+		}
+		err = migrator.Down(steps)
+	case "force":
+		if len(rest) < 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, convErr := strconv.Atoi(rest[1])
+		if convErr != nil {
+			fmt.Fprintln(os.Stderr, "force expects a migration version")
+			return 2
+		}
+		err = migrator.Force(version)
+	case "status":
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("current version: %d (dirty: %t)\n", status.Current, status.Dirty)
+	fmt.Printf("latest version:  %d\n", status.Latest)
+	if len(status.Pending) > 0 {
+		fmt.Printf("pending:         %v\n", status.Pending)
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"document-server/internal/api/models"
+	"document-server/internal/api/response"
+	"document-server/internal/service"
+	"document-server/internal/tracing"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+type APIKeyController struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyController(s *service.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeyService: s}
+}
+
+func (c *APIKeyController) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "APIKeyController.CreateAPIKey")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	var req models.APIKeyCreateRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, semerr.NewBadRequestError(err))
+		return
+	}
+	req.Token = requestToken(r, req.Token)
+
+	key, err := c.apiKeyService.CreateAPIKey(r.Context(), req)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusCreated, key)
+}
+
+func (c *APIKeyController) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "APIKeyController.ListAPIKeys")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	keys, err := c.apiKeyService.ListAPIKeys(r.Context(), token)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, map[string]interface{}{
+		"keys": keys,
+	})
+}
+
+func (c *APIKeyController) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "APIKeyController.RevokeAPIKey")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	id := mux.Vars(r)["id"]
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	if err := c.apiKeyService.RevokeAPIKey(r.Context(), token, id); err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithConfirm(w, http.StatusOK, map[string]bool{
+		id: true,
+	})
+}
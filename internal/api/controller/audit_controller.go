@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"document-server/internal/api/models"
+	"document-server/internal/api/response"
+	"document-server/internal/service"
+	"document-server/internal/tracing"
+	"encoding/json"
+	"net/http"
+)
+
+type AuditController struct {
+	auditService *service.AuditService
+}
+
+func NewAuditController(s *service.AuditService) *AuditController {
+	return &AuditController{auditService: s}
+}
+
+func (c *AuditController) Query(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AuditController.Query")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+	events, err := c.auditService.QueryAsAdmin(r.Context(), token, auditQuery(r))
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, map[string]interface{}{
+		"events": events,
+	})
+}
+
+// Export отдает события в NDJSON: по одному JSON объекту на строку, в порядке записи.
+func (c *AuditController) Export(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AuditController.Export")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	var written int
+	err := c.auditService.ExportAsAdmin(r.Context(), token, auditQuery(r), func(event models.AuditEventDTO) error {
+		if written == 0 {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+		}
+		written++
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		if flusher != nil && written%100 == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	switch {
+	case err != nil && written == 0:
+		response.RespondWithError(w, err)
+	case err != nil:
+		// Заголовки уже отправлены, клиент увидит оборванный поток; ошибку пишет в лог сервис.
+	case written == 0:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func auditQuery(r *http.Request) models.AuditQueryDTO {
+	q := r.URL.Query()
+	return models.AuditQueryDTO{
+		Actor:    q.Get("actor"),
+		Document: q.Get("document"),
+		Action:   q.Get("action"),
+		From:     q.Get("from"),
+		To:       q.Get("to"),
+		Limit:    q.Get("limit"),
+	}
+}
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"document-server/internal/api/response"
+	"document-server/internal/service"
+	"document-server/internal/tracing"
+	"net/http"
+)
+
+type ConfigController struct {
+	reloadService *service.ReloadService
+}
+
+func NewConfigController(s *service.ReloadService) *ConfigController {
+	return &ConfigController{reloadService: s}
+}
+
+func (c *ConfigController) Reload(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ConfigController.Reload")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	result, err := c.reloadService.ReloadAsAdmin(r.Context(), token)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, result)
+}
@@ -0,0 +1,39 @@
+package controller
+
+import "strings"
+
+// contentDisposition добавляет к ASCII имени точное имя в filename* (RFC 5987).
+func contentDisposition(disposition, filename string) string {
+	var fallback, encoded strings.Builder
+	for _, r := range filename {
+		switch {
+		case r < 0x20 || r > 0x7e || r == '"' || r == '\\':
+			fallback.WriteByte('_')
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(filename); i++ {
+		c := filename[i]
+		if isAttrChar(c) {
+			encoded.WriteByte(c)
+			continue
+		}
+		encoded.WriteByte('%')
+		encoded.WriteByte(hex[c>>4])
+		encoded.WriteByte(hex[c&0x0f])
+	}
+
+	return disposition + `; filename="` + fallback.String() + `"; filename*=UTF-8''` + encoded.String()
+}
+
+// isAttrChar - attr-char из RFC 5987: символы, которые не нужно кодировать.
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
@@ -0,0 +1,19 @@
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// digestHeader собирает заголовок Digest (RFC 3230) из hex сумм документа.
+func digestHeader(sha256Hex, md5Hex string) string {
+	var parts []string
+	if sum, err := hex.DecodeString(sha256Hex); err == nil && len(sum) > 0 {
+		parts = append(parts, "sha-256="+base64.StdEncoding.EncodeToString(sum))
+	}
+	if sum, err := hex.DecodeString(md5Hex); err == nil && len(sum) > 0 {
+		parts = append(parts, "md5="+base64.StdEncoding.EncodeToString(sum))
+	}
+	return strings.Join(parts, ",")
+}
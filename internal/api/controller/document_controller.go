@@ -2,12 +2,16 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"sync/atomic"
 
 	"document-server/internal/api/models"
 	"document-server/internal/api/response"
+	"document-server/internal/config"
 	"document-server/internal/service"
+	"document-server/internal/tracing"
 
 	"github.com/gorilla/mux"
 	"github.com/hedhyw/semerr/pkg/v1/semerr"
@@ -15,15 +19,34 @@ import (
 
 type DocumentController struct {
 	documentService *service.DocumentService
+	maxUploadSize   atomic.Int64
+	multipartMemory atomic.Int64
 }
 
-func NewDocumentController(documentService *service.DocumentService) *DocumentController {
-	return &DocumentController{documentService: documentService}
+func NewDocumentController(documentService *service.DocumentService, uploadCfg config.UploadConfig) *DocumentController {
+	c := &DocumentController{documentService: documentService}
+	c.SetUploadLimits(uploadCfg)
+	return c
+}
+
+func (c *DocumentController) SetUploadLimits(cfg config.UploadConfig) {
+	c.maxUploadSize.Store(int64(cfg.MaxSize))
+	c.multipartMemory.Store(int64(cfg.MultipartMemory))
 }
 
 func (c *DocumentController) UploadDocument(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(32 << 20) // 32MB
+	ctx, span := tracing.Start(r.Context(), "DocumentController.UploadDocument")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	r.Body = http.MaxBytesReader(w, r.Body, c.maxUploadSize.Load())
+	err := r.ParseMultipartForm(c.multipartMemory.Load())
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.RespondWithError(w, semerr.NewRequestEntityTooLargeError(err))
+			return
+		}
 		response.RespondWithError(w, semerr.NewBadRequestError(err))
 		return
 	}
@@ -35,24 +58,20 @@ func (c *DocumentController) UploadDocument(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	var fileBytes []byte
+	var file io.Reader
 	var filename string
 	var jsonData []byte
 
 	if meta.File {
-		file, header, err := r.FormFile("file")
+		formFile, header, err := r.FormFile("file")
 		if err != nil {
 			response.RespondWithError(w, semerr.NewBadRequestError(err))
 			return
 		}
-		defer file.Close()
+		defer formFile.Close()
 
+		file = formFile
 		filename = header.Filename
-		fileBytes, err = io.ReadAll(file)
-		if err != nil {
-			response.RespondWithError(w, semerr.NewBadRequestError(err))
-			return
-		}
 	} else {
 		jsonFile, _, err := r.FormFile("json")
 		if err == nil {
@@ -61,7 +80,9 @@ func (c *DocumentController) UploadDocument(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
-	doc, err := c.documentService.UploadDocument(r.Context(), meta, fileBytes, filename, jsonData)
+	meta.Token = requestToken(r, meta.Token)
+
+	doc, err := c.documentService.UploadDocument(r.Context(), meta, file, filename, jsonData)
 	if err != nil {
 		response.RespondWithError(w, err)
 		return
@@ -73,7 +94,11 @@ func (c *DocumentController) UploadDocument(w http.ResponseWriter, r *http.Reque
 }
 
 func (c *DocumentController) GetDocuments(w http.ResponseWriter, r *http.Request) {
-	token := r.URL.Query().Get("token")
+	ctx, span := tracing.Start(r.Context(), "DocumentController.GetDocuments")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	token := requestToken(r, r.URL.Query().Get("token"))
 	login := r.URL.Query().Get("login")
 	key := r.URL.Query().Get("key")
 	value := r.URL.Query().Get("value")
@@ -93,16 +118,50 @@ func (c *DocumentController) GetDocuments(w http.ResponseWriter, r *http.Request
 }
 
 func (c *DocumentController) GetDocument(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "DocumentController.GetDocument")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	id := mux.Vars(r)["id"]
-	doc, content, mimeType, err := c.documentService.GetDocument(r.Context(), id)
+	token := requestToken(r, r.URL.Query().Get("token"))
+	doc, content, mimeType, encoding, err := c.documentService.GetDocument(r.Context(), token, id, acceptsGzip(r.Header.Get("Accept-Encoding")))
 	if err != nil {
 		response.RespondWithError(w, err)
 		return
 	}
 
 	if doc.IsFile {
+		if content != nil {
+			defer content.Close()
+		}
 		w.Header().Set("Content-Type", mimeType)
-		w.Write(content)
+		// Даже открытый в браузере файл не получает скриптов и доступа к origin API.
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		disposition := dispositionFor(mimeType)
+		if doc.OriginalName.Valid {
+			w.Header().Set("Content-Disposition", contentDisposition(disposition, doc.OriginalName.String))
+		} else if disposition == "attachment" {
+			w.Header().Set("Content-Disposition", disposition)
+		}
+		w.Header().Set("Vary", "Accept-Encoding")
+		if encoding != "" {
+			// Digest описывает исходное содержимое, а не сжатое тело ответа.
+			w.Header().Set("Content-Encoding", encoding)
+		} else if digest := digestHeader(doc.SHA256.String, doc.MD5.String); digest != "" {
+			w.Header().Set("Digest", digest)
+		}
+		if content == nil {
+			return
+		}
+		// ServeContent отвечает на Range и условные запросы
+		if rs, ok := content.(io.ReadSeeker); ok && encoding == "" {
+			if doc.SHA256.Valid {
+				w.Header().Set("ETag", `"`+doc.SHA256.String+`"`)
+			}
+			http.ServeContent(w, r, "", doc.CreatedAt, rs)
+			return
+		}
+		io.Copy(w, content)
 		return
 	}
 
@@ -111,8 +170,13 @@ func (c *DocumentController) GetDocument(w http.ResponseWriter, r *http.Request)
 }
 
 func (c *DocumentController) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "DocumentController.DeleteDocument")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	id := mux.Vars(r)["id"]
-	err := c.documentService.DeleteDocument(r.Context(), id)
+	token := requestToken(r, r.URL.Query().Get("token"))
+	err := c.documentService.DeleteDocument(r.Context(), token, id)
 	if err != nil {
 		response.RespondWithError(w, err)
 		return
@@ -122,3 +186,25 @@ func (c *DocumentController) DeleteDocument(w http.ResponseWriter, r *http.Reque
 		"response": map[string]bool{id: true},
 	})
 }
+
+func (c *DocumentController) SetGrant(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "DocumentController.SetGrant")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	var req models.GrantRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, semerr.NewBadRequestError(err))
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	token := requestToken(r, r.URL.Query().Get("token"))
+	doc, err := c.documentService.SetGrant(r.Context(), token, id, req.Grant)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, doc)
+}
@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"document-server/internal/api/models"
+	"document-server/internal/api/response"
+	"document-server/internal/service"
+	"document-server/internal/tracing"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// EventController: WebSocket продолжает ленту по параметру last_event_id вместо заголовка Last-Event-ID.
+type EventController struct {
+	docService *service.DocumentService
+	heartbeat  time.Duration
+}
+
+func NewEventController(docService *service.DocumentService, heartbeat time.Duration) *EventController {
+	return &EventController{docService: docService, heartbeat: heartbeat}
+}
+
+func (c *EventController) Stream(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "EventController.Stream")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	feed, err := c.subscribe(r)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+	defer feed.Close()
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flush()
+
+	ticker := time.NewTicker(c.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-feed.C:
+			if !ok {
+				return
+			}
+			if err := writeServerSentEvent(w, event); err != nil {
+				return
+			}
+		}
+		flush()
+	}
+}
+
+func (c *EventController) WebSocket(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "EventController.WebSocket")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	feed, err := c.subscribe(r)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+	defer feed.Close()
+
+	server := websocket.Server{
+		// Origin не важен: токен проверен до апгрейда
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			c.pump(ws, feed)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// pump пишет события в соединение, пока клиент его не закроет.
+func (c *EventController) pump(ws *websocket.Conn, feed *service.Feed) {
+	// Входящие сообщения не нужны, чтение только замечает закрытие соединения.
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, ws)
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(c.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			ws.PayloadType = websocket.PingFrame
+			if _, err := ws.Write(nil); err != nil {
+				return
+			}
+		case event, ok := <-feed.C:
+			if !ok {
+				return
+			}
+			if err := websocket.JSON.Send(ws, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *EventController) subscribe(r *http.Request) (*service.Feed, error) {
+	query := r.URL.Query()
+	token := requestToken(r, query.Get("token"))
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("last_event_id")
+	}
+	return c.docService.Events(r.Context(), token, lastEventID)
+}
+
+// writeServerSentEvent не пишет поле event, чтобы все типы приходили в onmessage.
+func writeServerSentEvent(w io.Writer, event models.DocumentEventDTO) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"document-server/internal/api/response"
+	"document-server/internal/service"
+	"net/http"
+)
+
+type HealthController struct {
+	healthService *service.HealthService
+}
+
+func NewHealthController(s *service.HealthService) *HealthController {
+	return &HealthController{healthService: s}
+}
+
+func (c *HealthController) Liveness(w http.ResponseWriter, r *http.Request) {
+	response.RespondWithData(w, http.StatusOK, map[string]string{
+		"status": "ok",
+	})
+}
+
+func (c *HealthController) Readiness(w http.ResponseWriter, r *http.Request) {
+	readiness, ok := c.healthService.Readiness(r.Context())
+
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	response.RespondWithData(w, status, readiness)
+}
+
+func (c *HealthController) Status(w http.ResponseWriter, r *http.Request) {
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	status, err := c.healthService.Status(r.Context(), token)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, status)
+}
@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"mime"
+	"strings"
+)
+
+// inlineTypes безопасно показывать в браузере на origin API.
+var inlineTypes = []string{
+	"text/plain",
+	"application/pdf",
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"image/bmp",
+	"image/avif",
+}
+
+// dispositionFor отбрасывает параметры: тип старых документов записан со слов клиента.
+func dispositionFor(mimeType string) string {
+	mimeType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return "attachment"
+	}
+	if strings.HasPrefix(mimeType, "audio/") || strings.HasPrefix(mimeType, "video/") {
+		return "inline"
+	}
+	for _, t := range inlineTypes {
+		if t == mimeType {
+			return "inline"
+		}
+	}
+	return "attachment"
+}
@@ -0,0 +1,24 @@
+package controller
+
+import "testing"
+
+func TestDispositionFor(t *testing.T) {
+	tests := map[string]string{
+		"image/png":                 "inline",
+		"application/pdf":           "inline",
+		"text/plain":                "inline",
+		"text/plain; charset=utf-8": "inline",
+		"video/mp4":                 "inline",
+		"text/html":                 "attachment",
+		"text/html; charset=utf-8":  "attachment",
+		"image/svg+xml":             "attachment",
+		"application/xml":           "attachment",
+		"application/octet-stream":  "attachment",
+		"not a type":                "attachment",
+	}
+	for mimeType, want := range tests {
+		if got := dispositionFor(mimeType); got != want {
+			t.Errorf("dispositionFor(%q) = %q, want %q", mimeType, got, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"document-server/internal/api/models"
+	"document-server/internal/api/response"
+	"document-server/internal/service"
+	"document-server/internal/tracing"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+type QuotaController struct {
+	quotaService *service.QuotaService
+}
+
+func NewQuotaController(s *service.QuotaService) *QuotaController {
+	return &QuotaController{quotaService: s}
+}
+
+func (c *QuotaController) Usage(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "QuotaController.Usage")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	usage, err := c.quotaService.Usage(r.Context(), token)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, usage)
+}
+
+func (c *QuotaController) SetQuota(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "QuotaController.SetQuota")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	var req models.QuotaRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, semerr.NewBadRequestError(err))
+		return
+	}
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	usage, err := c.quotaService.SetQuotaAsAdmin(r.Context(), token, mux.Vars(r)["login"], req)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, usage)
+}
+
+func (c *QuotaController) SetGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "QuotaController.SetGroup")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	var req models.GroupRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, semerr.NewBadRequestError(err))
+		return
+	}
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	usage, err := c.quotaService.SetGroupAsAdmin(r.Context(), token, mux.Vars(r)["login"], req)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, usage)
+}
+
+func (c *QuotaController) SetGroupQuota(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "QuotaController.SetGroupQuota")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	var req models.QuotaRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, semerr.NewBadRequestError(err))
+		return
+	}
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	usage, err := c.quotaService.SetGroupQuotaAsAdmin(r.Context(), token, mux.Vars(r)["name"], req)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, usage)
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"document-server/internal/api/response"
+	"document-server/internal/service"
+	"document-server/internal/tracing"
+	"net/http"
+)
+
+type ReconcileController struct {
+	reconciler *service.Reconciler
+}
+
+func NewReconcileController(r *service.Reconciler) *ReconcileController {
+	return &ReconcileController{reconciler: r}
+}
+
+func (c *ReconcileController) Reconcile(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ReconcileController.Reconcile")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	report, err := c.reconciler.ReconcileAsAdmin(r.Context(), token)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, report)
+}
+
+func (c *ReconcileController) LastReport(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ReconcileController.LastReport")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	report, err := c.reconciler.LastReportAsAdmin(r.Context(), token)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, report)
+}
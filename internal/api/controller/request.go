@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+)
+
+// requestToken берет токен из запроса, а при его отсутствии - из заголовка Authorization.
+func requestToken(r *http.Request, token string) string {
+	if token != "" {
+		return token
+	}
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
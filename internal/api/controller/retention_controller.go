@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"document-server/internal/api/response"
+	"document-server/internal/service"
+	"document-server/internal/tracing"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type RetentionController struct {
+	retention *service.Retention
+}
+
+func NewRetentionController(r *service.Retention) *RetentionController {
+	return &RetentionController{retention: r}
+}
+
+func (c *RetentionController) SetHold(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "RetentionController.SetHold")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	c.setHold(w, r, true)
+}
+
+func (c *RetentionController) ClearHold(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "RetentionController.ClearHold")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	c.setHold(w, r, false)
+}
+
+func (c *RetentionController) setHold(w http.ResponseWriter, r *http.Request, hold bool) {
+	id := mux.Vars(r)["id"]
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	if _, err := c.retention.SetLegalHoldAsAdmin(r.Context(), token, id, hold); err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithConfirm(w, http.StatusOK, map[string]bool{"legal_hold": hold})
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"document-server/internal/api/response"
+	"document-server/internal/service"
+	"document-server/internal/tracing"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type TrashController struct {
+	documentService *service.DocumentService
+}
+
+func NewTrashController(documentService *service.DocumentService) *TrashController {
+	return &TrashController{documentService: documentService}
+}
+
+func (c *TrashController) List(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "TrashController.List")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+	docs, err := c.documentService.ListTrash(r.Context(), token, r.URL.Query().Get("limit"))
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"docs": docs,
+		},
+	})
+}
+
+func (c *TrashController) Restore(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "TrashController.Restore")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	id := mux.Vars(r)["id"]
+	token := requestToken(r, r.URL.Query().Get("token"))
+	if err := c.documentService.RestoreDocument(r.Context(), token, id); err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, map[string]interface{}{
+		"response": map[string]bool{id: true},
+	})
+}
+
+func (c *TrashController) Purge(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "TrashController.Purge")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	id := mux.Vars(r)["id"]
+	token := requestToken(r, r.URL.Query().Get("token"))
+	if err := c.documentService.PurgeDocument(r.Context(), token, id); err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, map[string]interface{}{
+		"response": map[string]bool{id: true},
+	})
+}
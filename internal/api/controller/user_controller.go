@@ -4,6 +4,7 @@ import (
 	"document-server/internal/api/models"
 	"document-server/internal/api/response"
 	"document-server/internal/service"
+	"document-server/internal/tracing"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -21,6 +22,10 @@ func NewUserController(s *service.UserService) *UserController {
 }
 
 func (c *UserController) Register(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UserController.Register")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	var req models.RegisterRequestDTO
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		response.RespondWithError(w, semerr.NewBadRequestError(err))
@@ -37,6 +42,10 @@ func (c *UserController) Register(w http.ResponseWriter, r *http.Request) {
 }
 
 func (c *UserController) Authenticate(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UserController.Authenticate")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	var req models.AuthRequestDTO
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		response.RespondWithError(w, semerr.NewBadRequestError(err))
@@ -53,6 +62,10 @@ func (c *UserController) Authenticate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (c *UserController) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UserController.Logout")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	vars := mux.Vars(r)
 	token := vars["token"]
 	if token == "" {
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"document-server/internal/api/models"
+	"document-server/internal/api/response"
+	"document-server/internal/service"
+	"document-server/internal/tracing"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+type WebhookController struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookController(s *service.WebhookService) *WebhookController {
+	return &WebhookController{webhookService: s}
+}
+
+func (c *WebhookController) Create(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "WebhookController.Create")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	var req models.WebhookCreateRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.RespondWithError(w, semerr.NewBadRequestError(err))
+		return
+	}
+	req.Token = requestToken(r, req.Token)
+
+	webhook, err := c.webhookService.Create(r.Context(), req)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusCreated, webhook)
+}
+
+func (c *WebhookController) List(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "WebhookController.List")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	webhooks, err := c.webhookService.List(r.Context(), token)
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, map[string]interface{}{
+		"webhooks": webhooks,
+	})
+}
+
+func (c *WebhookController) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "WebhookController.Delete")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	id := mux.Vars(r)["id"]
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	if err := c.webhookService.Delete(r.Context(), token, id); err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithConfirm(w, http.StatusOK, map[string]bool{
+		id: true,
+	})
+}
+
+func (c *WebhookController) Deliveries(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "WebhookController.Deliveries")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	query := r.URL.Query()
+	token := requestToken(r, query.Get("token"))
+
+	deliveries, err := c.webhookService.Deliveries(r.Context(), token, mux.Vars(r)["id"], query.Get("status"), query.Get("limit"))
+	if err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithData(w, http.StatusOK, map[string]interface{}{
+		"deliveries": deliveries,
+	})
+}
+
+func (c *WebhookController) Redeliver(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "WebhookController.Redeliver")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	vars := mux.Vars(r)
+	token := requestToken(r, r.URL.Query().Get("token"))
+
+	if err := c.webhookService.Redeliver(r.Context(), token, vars["id"], vars["delivery"]); err != nil {
+		response.RespondWithError(w, err)
+		return
+	}
+
+	response.RespondWithConfirm(w, http.StatusAccepted, map[string]bool{
+		vars["delivery"]: true,
+	})
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"document-server/internal/logger"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AccessLog привязывает к контексту логгер с request_id и пишет одну строку на запрос.
+func AccessLog(base *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := wrapResponseWriter(w)
+
+			reqLogger := base.With(slog.String("request_id", r.Header.Get(RequestIDHeader)))
+			if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+				reqLogger = reqLogger.With(slog.String("trace_id", sc.TraceID().String()))
+			}
+			ctx := logger.WithContext(r.Context(), reqLogger)
+
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			route := "unmatched"
+			if matched, ok := ctx.Value(routeKey{}).(*matchedRoute); ok {
+				route = matched.template
+			}
+			reqLogger.Info("http request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", rw.status),
+				slog.Duration("duration", time.Since(start)),
+				slog.Int64("bytes", rw.bytes),
+				slog.String("remote_ip", clientIP(r)),
+				slog.String("user_agent", r.UserAgent()),
+			)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"document-server/internal/service"
+	"net"
+	"net/http"
+)
+
+// ClientIP должен стоять после RequestID.
+func ClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := service.WithClientIP(r.Context(), clientIP(r))
+		ctx = service.WithRequestMeta(ctx, service.RequestMeta{UserAgent: r.UserAgent(), RequestID: r.Header.Get(RequestIDHeader)})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
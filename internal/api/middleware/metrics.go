@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"document-server/internal/metrics"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type routeKey struct{}
+
+type matchedRoute struct {
+	template string
+}
+
+// Metrics группирует запросы по шаблону маршрута, чтобы id не раздували число серий.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := &matchedRoute{template: "unmatched"}
+		rw := wrapResponseWriter(w)
+
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), routeKey{}, route)))
+
+		metrics.ObserveHTTPRequest(r.Method, route.template, rw.status, time.Since(start))
+	})
+}
+
+// CaptureRoute подключается через mux.Router.Use и сообщает Metrics совпавший маршрут.
+func CaptureRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeKey{}).(*matchedRoute); ok {
+			if current := mux.CurrentRoute(r); current != nil {
+				if tmpl, err := current.GetPathTemplate(); err == nil {
+					route.template = tmpl
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
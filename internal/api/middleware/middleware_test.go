@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// logRecorder собирает JSON записи slog для проверки полей.
+type logRecorder struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *logRecorder) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *logRecorder) records(t *testing.T) []map[string]any {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(l.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func newRecordingLogger() (*slog.Logger, *logRecorder) {
+	rec := &logRecorder{}
+	return slog.New(slog.NewJSONHandler(rec, nil)), rec
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get(RequestIDHeader)
+	}))
+
+	cases := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "propagated", incoming: "req-42_a.b:c", keep: true},
+		{name: "missing", incoming: ""},
+		{name: "invalid characters", incoming: "bad id\n"},
+		{name: "too long", incoming: strings.Repeat("a", 129)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set(RequestIDHeader, tc.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			got := rr.Header().Get(RequestIDHeader)
+			if got != seen {
+				t.Fatalf("response id %q, handler saw %q", got, seen)
+			}
+			if tc.keep {
+				if got != tc.incoming {
+					t.Fatalf("id = %q, want %q", got, tc.incoming)
+				}
+				return
+			}
+			if _, err := uuid.Parse(got); err != nil {
+				t.Fatalf("generated id %q is not a UUID: %v", got, err)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	logger, logs := newRecordingLogger()
+	handler := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("Content-Type = %q, want JSON", ct)
+	}
+	var body struct {
+		Error *struct {
+			Code int    `json:"code"`
+			Text string `json:"text"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q is not JSON: %v", rr.Body.String(), err)
+	}
+	if body.Error == nil || body.Error.Code != http.StatusInternalServerError {
+		t.Fatalf("body = %s, want error with code 500", rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "boom") {
+		t.Fatalf("panic value leaked to the client: %s", rr.Body.String())
+	}
+
+	records := logs.records(t)
+	if len(records) != 1 || records[0]["msg"] != "panic recovered" || records[0]["panic"] != "boom" {
+		t.Fatalf("log records = %v, want one panic record", records)
+	}
+	if stack, _ := records[0]["stack"].(string); stack == "" {
+		t.Fatal("panic record has no stack")
+	}
+}
+
+func TestRecoverAfterHeaderWritten(t *testing.T) {
+	logger, _ := newRecordingLogger()
+	handler := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusAccepted || rr.Body.Len() != 0 {
+		t.Fatalf("status = %d, body = %q; want untouched 202", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	logger, logs := newRecordingLogger()
+
+	r := mux.NewRouter()
+	r.Use(CaptureRoute)
+	r.HandleFunc("/api/documents/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := Chain(r, RequestID, Metrics, AccessLog(logger))
+
+	cases := []struct {
+		path   string
+		id     string
+		route  string
+		status float64
+	}{
+		{path: "/api/documents/123", id: "req-1", route: "/api/documents/{id}", status: http.StatusNotFound},
+		{path: "/missing", id: "req-2", route: "unmatched", status: http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set(RequestIDHeader, tc.id)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	records := logs.records(t)
+	if len(records) != len(cases) {
+		t.Fatalf("log records = %v, want %d", records, len(cases))
+	}
+	for i, tc := range cases {
+		rec := records[i]
+		if rec["msg"] != "http request" || rec["route"] != tc.route || rec["status"] != tc.status ||
+			rec["request_id"] != tc.id || rec["path"] != tc.path {
+			t.Fatalf("record %d = %v", i, rec)
+		}
+	}
+}
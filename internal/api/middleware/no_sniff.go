@@ -0,0 +1,11 @@
+package middleware
+
+import "net/http"
+
+// NoSniff запрещает браузеру угадывать тип ответа вместо заявленного Content-Type.
+func NoSniff(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"document-server/internal/api/response"
+	"document-server/internal/config"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+	"golang.org/x/time/rate"
+)
+
+const limiterIdleTTL = 10 * time.Minute
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter ограничивает частоту запросов с одного IP. Нулевой лимит отключает ограничение.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{clients: map[string]*clientLimiter{}}
+	l.Configure(cfg)
+	return l
+}
+
+func (l *RateLimiter) Configure(cfg config.RateLimitConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = rate.Limit(cfg.RequestsPerSecond)
+	l.burst = cfg.Burst
+	for _, c := range l.clients {
+		c.limiter.SetLimit(l.limit)
+		c.limiter.SetBurst(l.burst)
+	}
+}
+
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.allow(clientIP(r)) {
+			w.Header().Set("Retry-After", "1")
+			response.RespondWithError(w, semerr.NewTooManyRequestsError(errors.New("rate limit exceeded")))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *RateLimiter) allow(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > time.Minute {
+		for key, c := range l.clients {
+			if now.Sub(c.lastSeen) > limiterIdleTTL {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = c
+	}
+	c.lastSeen = now
+	return c.limiter.AllowN(now, 1)
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"document-server/internal/api/response"
+	"document-server/internal/logger"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// Recover превращает панику в обработчике в JSON ответ 500.
+func Recover(base *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := wrapResponseWriter(w)
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				logger.FromContext(r.Context(), base).Error("panic recovered",
+					slog.String("panic", fmt.Sprint(rec)),
+					slog.String("stack", string(debug.Stack())),
+				)
+
+				if rw.wroteHeader {
+					return
+				}
+				response.RespondWithError(rw, semerr.NewInternalServerError(errors.New("internal server error")))
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
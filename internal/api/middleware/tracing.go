@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing должен стоять после Metrics, чтобы назвать спан по шаблону маршрута.
+func Tracing(next http.Handler) http.Handler {
+	tracer := otel.Tracer("document-server")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				attribute.String("http.request_id", r.Header.Get(RequestIDHeader)),
+			),
+		)
+		defer span.End()
+
+		rw := wrapResponseWriter(w)
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		if route, ok := ctx.Value(routeKey{}).(*matchedRoute); ok {
+			span.SetName(r.Method + " " + route.template)
+			span.SetAttributes(semconv.HTTPRoute(route.template))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+		if rw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rw.status))
+		}
+	})
+}
@@ -43,19 +43,287 @@ type DocumentUploadMetaDTO struct {
 	Token  string   `json:"token"`
 	Mime   string   `json:"mime"`
 	Grant  []string `json:"grant"`
+	// SHA256 и MD5 - ожидаемые клиентом суммы в hex; при расхождении загрузка отклоняется.
+	SHA256 string `json:"sha256,omitempty"`
+	MD5    string `json:"md5,omitempty"`
+	// ExpiresAt - когда документ будет удален; LegalHold (только для администраторов) запрещает удаление.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LegalHold bool       `json:"legal_hold,omitempty"`
 }
 
 type DocumentResponseDTO struct {
-	JSON json.RawMessage `json:"json,omitempty"`
-	File string          `json:"file,omitempty"`
+	JSON       json.RawMessage `json:"json,omitempty"`
+	File       string          `json:"file,omitempty"`
+	SHA256     string          `json:"sha256,omitempty"`
+	MD5        string          `json:"md5,omitempty"`
+	Size       int64           `json:"size,omitempty"`
+	StoredSize int64           `json:"stored_size,omitempty"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+	LegalHold  bool            `json:"legal_hold,omitempty"`
 }
 
 type DocumentListItemDTO struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Mime      string     `json:"mime"`
+	File      bool       `json:"file"`
+	Public    bool       `json:"public"`
+	CreatedAt time.Time  `json:"created_at"`
+	Grant     []string   `json:"grant,omitempty"`
+	Filename  string     `json:"filename,omitempty"`
+	SHA256    string     `json:"sha256,omitempty"`
+	MD5       string     `json:"md5,omitempty"`
+	Size      int64      `json:"size,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LegalHold bool       `json:"legal_hold,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type APIKeyCreateRequestDTO struct {
+	Token      string     `json:"token"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	AllowedIPs []string   `json:"allowed_ips,omitempty"`
+}
+
+type APIKeyDTO struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type APIKeyCreateResponseDTO struct {
+	APIKeyDTO
+	Key string `json:"key"`
+}
+
+type HealthCheckDTO struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ReadinessDTO struct {
+	Status string                    `json:"status"`
+	Checks map[string]HealthCheckDTO `json:"checks"`
+}
+
+type BuildInfoDTO struct {
+	GoVersion string `json:"go_version"`
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+type CacheStatsDTO struct {
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+	Evictions  uint64 `json:"evictions"`
+	Size       int64  `json:"size"`
+	MaxEntries int    `json:"max_entries"`
+	Errors     uint64 `json:"errors,omitempty"`
+}
+
+type DBPoolStatsDTO struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+}
+
+type StatusDTO struct {
+	Readiness ReadinessDTO   `json:"readiness"`
+	StartedAt time.Time      `json:"started_at"`
+	Uptime    string         `json:"uptime"`
+	Build     BuildInfoDTO   `json:"build"`
+	Cache     CacheStatsDTO  `json:"cache"`
+	DBPool    DBPoolStatsDTO `json:"db_pool"`
+}
+
+type ConfigReloadDTO struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+type OrphanFileDTO struct {
+	Name          string    `json:"name"`
+	Size          int64     `json:"size"`
+	ModTime       time.Time `json:"mod_time"`
+	QuarantinedTo string    `json:"quarantined_to,omitempty"`
+	Removed       bool      `json:"removed,omitempty"`
+}
+
+type MissingContentDTO struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type ReconcileReportDTO struct {
+	Action       string              `json:"action"`
+	StartedAt    time.Time           `json:"started_at"`
+	Duration     string              `json:"duration"`
+	ScannedFiles int                 `json:"scanned_files"`
+	ScannedRows  int                 `json:"scanned_rows"`
+	Orphans      []OrphanFileDTO     `json:"orphans"`
+	Missing      []MissingContentDTO `json:"missing"`
+	FreedBytes   int64               `json:"freed_bytes,omitempty"`
+}
+
+type ScrubMismatchDTO struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ScrubReportDTO struct {
+	StartedAt       time.Time          `json:"started_at"`
+	Duration        string             `json:"duration"`
+	Checked         int                `json:"checked"`
+	OK              int                `json:"ok"`
+	Unverified      int                `json:"unverified"`
+	Backfilled      int                `json:"backfilled"`
+	SizesBackfilled int                `json:"sizes_backfilled"`
+	Failed          []ScrubMismatchDTO `json:"failed"`
+}
+
+type KeyRotationFailureDTO struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	KeyID string `json:"key_id"`
+	Error string `json:"error"`
+}
+
+type KeyRotationReportDTO struct {
+	ActiveKeyID string                  `json:"active_key_id"`
+	Checked     int                     `json:"checked"`
+	Rewrapped   int                     `json:"rewrapped"`
+	Plaintext   int                     `json:"plaintext"`
+	Failed      []KeyRotationFailureDTO `json:"failed"`
+}
+
+type UsageDTO struct {
+	Login        string         `json:"login"`
+	Bytes        int64          `json:"bytes"`
+	Documents    int64          `json:"documents"`
+	MaxBytes     int64          `json:"max_bytes"`
+	MaxDocuments int64          `json:"max_documents"`
+	Group        *GroupUsageDTO `json:"group,omitempty"`
+}
+
+// GroupUsageDTO - общее использование и квота группы пользователя.
+type GroupUsageDTO struct {
+	Name         string `json:"name"`
+	Bytes        int64  `json:"bytes"`
+	Documents    int64  `json:"documents"`
+	MaxBytes     int64  `json:"max_bytes"`
+	MaxDocuments int64  `json:"max_documents"`
+}
+
+// GroupRequestDTO: пустой group убирает пользователя из группы.
+type GroupRequestDTO struct {
+	Group string `json:"group"`
+}
+
+// QuotaRequestDTO: отсутствующее поле возвращает значение из конфигурации, 0 снимает ограничение.
+type QuotaRequestDTO struct {
+	MaxBytes     *int64 `json:"max_bytes"`
+	MaxDocuments *int64 `json:"max_documents"`
+}
+
+type GrantRequestDTO struct {
+	Grant []string `json:"grant"`
+}
+
+type AuditEventDTO struct {
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+	Actor     string    `json:"actor,omitempty"`
+	Target    string    `json:"target,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// AuditQueryDTO - параметры выборки журнала аудита; From и To в RFC 3339.
+type AuditQueryDTO struct {
+	Actor    string
+	Document string
+	Action   string
+	From     string
+	To       string
+	Limit    string
+}
+
+type WebhookCreateRequestDTO struct {
+	Token  string   `json:"token"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+type WebhookDTO struct {
 	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Mime      string    `json:"mime"`
-	File      bool      `json:"file"`
-	Public    bool      `json:"public"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
 	CreatedAt time.Time `json:"created_at"`
-	Grant     []string  `json:"grant,omitempty"`
+}
+
+// WebhookCreateResponseDTO: секрет для проверки подписи отдается только при создании.
+type WebhookCreateResponseDTO struct {
+	WebhookDTO
+	Secret string `json:"secret"`
+}
+
+type WebhookDeliveryDTO struct {
+	ID            int64      `json:"id"`
+	EventID       int64      `json:"event_id"`
+	Event         string     `json:"event"`
+	Document      string     `json:"document"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatus    int        `json:"last_status,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// WebhookPayloadDTO - тело запроса, который получает подписчик.
+type WebhookPayloadDTO struct {
+	ID         int64                     `json:"id"`
+	Type       string                    `json:"type"`
+	OccurredAt time.Time                 `json:"occurred_at"`
+	Document   WebhookPayloadDocumentDTO `json:"document"`
+}
+
+type WebhookPayloadDocumentDTO struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Grant []string `json:"grant"`
+}
+
+// DocumentEventDTO - событие ленты /api/events; Grant - список доступа после события.
+type DocumentEventDTO struct {
+	ID       int64     `json:"id,omitempty"`
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Document string    `json:"document,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Grant    []string  `json:"grant,omitempty"`
 }
@@ -21,6 +21,11 @@ func RespondWithError(w http.ResponseWriter, err error) {
 	}
 
 	status := httperr.Code(err)
+	// Статусы, которых нет в semerr (например, 507), ошибка сообщает сама.
+	var coded interface{ HTTPStatus() int }
+	if errors.As(err, &coded) {
+		status = coded.HTTPStatus()
+	}
 
 	response := models.APIResponse{
 		Error: &models.APIError{
@@ -2,6 +2,8 @@ package api
 
 import (
 	"document-server/internal/api/controller"
+	"document-server/internal/api/middleware"
+	"log/slog"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -9,17 +11,44 @@ import (
 
 type Router struct {
 	*mux.Router
+	root    *mux.Router
+	handler http.Handler
 }
 
-func NewRouter() (*Router, error) {
+func NewRouter(logger *slog.Logger) (*Router, error) {
 	r := mux.NewRouter()
+	r.Use(middleware.CaptureRoute)
 	api := r.PathPrefix("/api").Subrouter()
 
 	return &Router{
 		Router: api,
+		root:   r,
+		handler: middleware.Chain(r,
+			middleware.RequestID,
+			middleware.NoSniff,
+			middleware.ClientIP,
+			middleware.Metrics,
+			middleware.Tracing,
+			middleware.AccessLog(logger),
+			middleware.Recover(logger),
+		),
 	}, nil
 }
 
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+func (r *Router) SetMetricsRoutes(handler http.Handler) {
+	r.root.Handle("/metrics", handler).Methods(http.MethodGet)
+}
+
+func (r *Router) SetHealthRoutes(controller *controller.HealthController) {
+	r.root.HandleFunc("/healthz", controller.Liveness).Methods(http.MethodGet, http.MethodHead)
+	r.root.HandleFunc("/readyz", controller.Readiness).Methods(http.MethodGet, http.MethodHead)
+	r.root.HandleFunc("/status", controller.Status).Methods(http.MethodGet)
+}
+
 func (r *Router) SetUserRoutes(controller *controller.UserController) {
 	r.HandleFunc("/auth", controller.Authenticate).Methods(http.MethodPost)
 
@@ -37,4 +66,75 @@ func (r *Router) SetDocsRoutes(controller *controller.DocumentController) {
 	docs.HandleFunc("", controller.UploadDocument).Methods(http.MethodPost)
 	docs.HandleFunc("/{id}", controller.GetDocument).Methods(http.MethodGet, http.MethodHead)
 	docs.HandleFunc("/{id}", controller.DeleteDocument).Methods(http.MethodDelete)
+	docs.HandleFunc("/{id}/grant", controller.SetGrant).Methods(http.MethodPut)
+}
+
+func (r *Router) SetAPIKeyRoutes(controller *controller.APIKeyController) {
+	keys := r.PathPrefix("/keys").Subrouter()
+
+	keys.HandleFunc("", controller.ListAPIKeys).Methods(http.MethodGet)
+	keys.HandleFunc("", controller.CreateAPIKey).Methods(http.MethodPost)
+	keys.HandleFunc("/{id}", controller.RevokeAPIKey).Methods(http.MethodDelete)
+}
+
+func (r *Router) SetAdminRoutes(controller *controller.ConfigController) {
+	admin := r.PathPrefix("/admin").Subrouter()
+
+	admin.HandleFunc("/config/reload", controller.Reload).Methods(http.MethodPost)
+}
+
+func (r *Router) SetReconcileRoutes(controller *controller.ReconcileController) {
+	admin := r.PathPrefix("/admin").Subrouter()
+
+	admin.HandleFunc("/reconcile", controller.LastReport).Methods(http.MethodGet)
+	admin.HandleFunc("/reconcile", controller.Reconcile).Methods(http.MethodPost)
+}
+
+func (r *Router) SetQuotaRoutes(controller *controller.QuotaController) {
+	r.HandleFunc("/users/me/usage", controller.Usage).Methods(http.MethodGet)
+
+	admin := r.PathPrefix("/admin").Subrouter()
+
+	admin.HandleFunc("/users/{login}/quota", controller.SetQuota).Methods(http.MethodPut)
+	admin.HandleFunc("/users/{login}/group", controller.SetGroup).Methods(http.MethodPut)
+	admin.HandleFunc("/groups/{name}/quota", controller.SetGroupQuota).Methods(http.MethodPut)
+}
+
+func (r *Router) SetRetentionRoutes(controller *controller.RetentionController) {
+	admin := r.PathPrefix("/admin").Subrouter()
+
+	admin.HandleFunc("/docs/{id}/hold", controller.SetHold).Methods(http.MethodPut)
+	admin.HandleFunc("/docs/{id}/hold", controller.ClearHold).Methods(http.MethodDelete)
+}
+
+func (r *Router) SetTrashRoutes(controller *controller.TrashController) {
+	trash := r.PathPrefix("/trash").Subrouter()
+
+	trash.HandleFunc("", controller.List).Methods(http.MethodGet)
+	trash.HandleFunc("/{id}/restore", controller.Restore).Methods(http.MethodPost)
+	trash.HandleFunc("/{id}", controller.Purge).Methods(http.MethodDelete)
+}
+
+func (r *Router) SetAuditRoutes(controller *controller.AuditController) {
+	admin := r.PathPrefix("/admin").Subrouter()
+
+	admin.HandleFunc("/audit", controller.Query).Methods(http.MethodGet)
+	admin.HandleFunc("/audit/export", controller.Export).Methods(http.MethodGet)
+}
+
+func (r *Router) SetWebhookRoutes(controller *controller.WebhookController) {
+	webhooks := r.PathPrefix("/webhooks").Subrouter()
+
+	webhooks.HandleFunc("", controller.List).Methods(http.MethodGet)
+	webhooks.HandleFunc("", controller.Create).Methods(http.MethodPost)
+	webhooks.HandleFunc("/{id}", controller.Delete).Methods(http.MethodDelete)
+	webhooks.HandleFunc("/{id}/deliveries", controller.Deliveries).Methods(http.MethodGet)
+	webhooks.HandleFunc("/{id}/deliveries/{delivery}/redeliver", controller.Redeliver).Methods(http.MethodPost)
+}
+
+func (r *Router) SetEventRoutes(controller *controller.EventController) {
+	events := r.PathPrefix("/events").Subrouter()
+
+	events.HandleFunc("", controller.Stream).Methods(http.MethodGet)
+	events.HandleFunc("/ws", controller.WebSocket).Methods(http.MethodGet)
 }
@@ -1,56 +1,183 @@
 package cache
 
 import (
+	"context"
 	"document-server/internal/config"
 	storage "document-server/internal/storage/document"
+	"document-server/internal/tracing"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // cacheItem - это обертка для хранения значения и времени его истечения
 type cacheItem struct {
 	value     *storage.Document
+	storedAt  time.Time
 	expiresAt time.Time
 }
 
+type Stats struct {
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+	Evictions  uint64 `json:"evictions"`
+	Size       int64  `json:"size"`
+	MaxEntries int    `json:"max_entries"`
+	Errors     uint64 `json:"errors,omitempty"`
+}
+
 type InMemoryCache struct {
-	store sync.Map
-	ttl   time.Duration
+	store      sync.Map
+	ttl        atomic.Int64
+	maxAge     atomic.Int64
+	maxEntries atomic.Int64
+
+	size      atomic.Int64
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+
+	invalidations invalidations
 }
 
 func NewInMemoryCache(cfg config.CacheConfig) *InMemoryCache {
-	return &InMemoryCache{
-		ttl: time.Duration(cfg.TTL * int(time.Minute)),
-	}
+	c := &InMemoryCache{}
+	c.Configure(cfg)
+	return c
+}
+
+// Configure меняет TTL и лимит записей на лету; уже сохраненные записи живут со своим сроком.
+func (c *InMemoryCache) Configure(cfg config.CacheConfig) {
+	c.ttl.Store(int64(time.Duration(cfg.TTL) * time.Minute))
+	c.maxEntries.Store(int64(cfg.MaxEntries))
 }
 
-func (c *InMemoryCache) Get(key string) (*storage.Document, bool) {
+func (c *InMemoryCache) Get(ctx context.Context, key string) (*storage.Document, bool) {
+	_, span := tracing.Start(ctx, "InMemoryCache.Get", attribute.String("cache.key", key))
+	defer span.End()
+
+	doc, ok := c.get(key)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	return doc, ok
+}
+
+func (c *InMemoryCache) get(key string) (*storage.Document, bool) {
 	itemInterface, ok := c.store.Load(key)
 	if !ok {
+		c.misses.Add(1)
 		return nil, false
 	}
 
 	item, ok := itemInterface.(cacheItem)
 	if !ok {
+		c.misses.Add(1)
 		return nil, false
 	}
 
-	if time.Now().After(item.expiresAt) {
-		c.store.Delete(key)
+	now := time.Now()
+	if maxAge := c.maxAge.Load(); now.After(item.expiresAt) || (maxAge > 0 && now.Sub(item.storedAt) > time.Duration(maxAge)) {
+		c.evict(key, itemInterface)
+		c.misses.Add(1)
 		return nil, false
 	}
 
+	c.hits.Add(1)
 	return item.value, true
 }
 
-func (c *InMemoryCache) Set(key string, doc *storage.Document) {
-	expiresAt := time.Now().Add(c.ttl)
-	c.store.Store(key, cacheItem{
+func (c *InMemoryCache) Set(ctx context.Context, key string, doc *storage.Document) {
+	_, span := tracing.Start(ctx, "InMemoryCache.Set", attribute.String("cache.key", key))
+	defer span.End()
+
+	now := time.Now()
+	_, loaded := c.store.Swap(key, cacheItem{
 		value:     doc,
-		expiresAt: expiresAt,
+		storedAt:  now,
+		expiresAt: now.Add(time.Duration(c.ttl.Load())),
 	})
+	size := c.size.Load()
+	if !loaded {
+		size = c.size.Add(1)
+	}
+	if maxEntries := c.maxEntries.Load(); maxEntries > 0 && size > maxEntries {
+		c.shrink(key, maxEntries)
+	}
 }
 
-func (c *InMemoryCache) Delete(key string) {
-	c.store.Delete(key)
+func (c *InMemoryCache) Delete(ctx context.Context, key string) {
+	_, span := tracing.Start(ctx, "InMemoryCache.Delete", attribute.String("cache.key", key))
+	defer span.End()
+
+	c.invalidations.delete(key)
+	if _, loaded := c.store.LoadAndDelete(key); loaded {
+		c.size.Add(-1)
+	}
+}
+
+// SetLoaded не сохраняет документ, если запись удалялась после loadedAt.
+func (c *InMemoryCache) SetLoaded(ctx context.Context, key string, doc *storage.Document, loadedAt time.Time) {
+	if c.invalidatedSince(key, loadedAt) {
+		return
+	}
+	c.Set(ctx, key, doc)
+}
+
+func (c *InMemoryCache) invalidatedSince(key string, t time.Time) bool {
+	return c.invalidations.since(key, t)
+}
+
+// LimitTTL действует и на уже сохраненные записи; 0 снимает ограничение.
+func (c *InMemoryCache) LimitTTL(d time.Duration) {
+	c.maxAge.Store(int64(d))
+}
+
+// Clear удаляет все записи.
+func (c *InMemoryCache) Clear() {
+	c.invalidations.clear()
+	c.store.Range(func(k, v any) bool {
+		if c.store.CompareAndDelete(k, v) {
+			c.size.Add(-1)
+		}
+		return true
+	})
+}
+
+func (c *InMemoryCache) Stats() Stats {
+	return Stats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Evictions:  c.evictions.Load(),
+		Size:       c.size.Load(),
+		MaxEntries: int(c.maxEntries.Load()),
+	}
+}
+
+// shrink удаляет сначала истекшие записи, затем произвольные, кроме keep.
+func (c *InMemoryCache) shrink(keep string, maxEntries int64) {
+	now := time.Now()
+	c.store.Range(func(k, v any) bool {
+		if item, ok := v.(cacheItem); ok && now.After(item.expiresAt) {
+			c.evict(k, v)
+		}
+		return true
+	})
+
+	c.store.Range(func(k, v any) bool {
+		if c.size.Load() <= maxEntries {
+			return false
+		}
+		if k != keep {
+			c.evict(k, v)
+		}
+		return true
+	})
+}
+
+func (c *InMemoryCache) evict(key, value any) {
+	if c.store.CompareAndDelete(key, value) {
+		c.size.Add(-1)
+		c.evictions.Add(1)
+	}
 }
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"document-server/internal/config"
+	storage "document-server/internal/storage/document"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestSetLoadedSkipsInvalidated: чтение из базы, затем Delete, затем SetLoaded.
+func TestSetLoadedSkipsInvalidated(t *testing.T) {
+	ctx := context.Background()
+	c := NewInMemoryCache(config.CacheConfig{TTL: 10, MaxEntries: 10})
+	doc := &storage.Document{ID: uuid.New(), Name: "stale"}
+
+	now := time.Now()
+	c.invalidations.now = func() time.Time { return now }
+	tick := func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+
+	loadedAt := tick()
+	tick()
+	c.Delete(ctx, "document:a")
+	c.SetLoaded(ctx, "document:a", doc, loadedAt)
+	if _, ok := c.Get(ctx, "document:a"); ok {
+		t.Error("document read before Delete was cached")
+	}
+
+	loadedAt = tick()
+	tick()
+	c.Clear()
+	c.SetLoaded(ctx, "document:b", doc, loadedAt)
+	if _, ok := c.Get(ctx, "document:b"); ok {
+		t.Error("document read before Clear was cached")
+	}
+
+	c.SetLoaded(ctx, "document:a", doc, tick())
+	if _, ok := c.Get(ctx, "document:a"); !ok {
+		t.Error("document read after Delete was not cached")
+	}
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// invalidationWindow - сколько помнится Delete; более старые чтения не кэшируются.
+const invalidationWindow = time.Minute
+
+// invalidations помнит время последних Delete по ключам и последнего Clear.
+type invalidations struct {
+	now      func() time.Time
+	deleted  sync.Map
+	cleared  atomic.Int64
+	prunedAt atomic.Int64
+}
+
+func (v *invalidations) clock() time.Time {
+	if v.now != nil {
+		return v.now()
+	}
+	return time.Now()
+}
+
+func (v *invalidations) delete(key string) {
+	now := v.clock()
+	v.deleted.Store(key, now)
+	v.prune(now)
+}
+
+func (v *invalidations) clear() {
+	v.cleared.Store(v.clock().UnixNano())
+}
+
+// since сообщает, удалялась ли запись после t.
+func (v *invalidations) since(key string, t time.Time) bool {
+	if v.clock().Sub(t) > invalidationWindow || v.cleared.Load() >= t.UnixNano() {
+		return true
+	}
+	deletedAt, ok := v.deleted.Load(key)
+	return ok && !deletedAt.(time.Time).Before(t)
+}
+
+// prune забывает Delete старше invalidationWindow, не чаще раза за окно.
+func (v *invalidations) prune(now time.Time) {
+	last := v.prunedAt.Load()
+	if now.UnixNano()-last < int64(invalidationWindow) || !v.prunedAt.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	v.deleted.Range(func(k, t any) bool {
+		if now.Sub(t.(time.Time)) > invalidationWindow {
+			v.deleted.CompareAndDelete(k, t)
+		}
+		return true
+	})
+}
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"document-server/internal/config"
+	storage "document-server/internal/storage/document"
+	"document-server/internal/tracing"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// LRUCache - локальный уровень TieredCache.
+type LRUCache struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List // от недавно прочитанных к давно не читавшимся
+	ttl        time.Duration
+	maxAge     time.Duration
+	maxEntries int
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
+	invalidations invalidations
+}
+
+type lruEntry struct {
+	key  string
+	item cacheItem
+}
+
+func NewLRUCache(cfg config.CacheConfig) *LRUCache {
+	c := &LRUCache{items: make(map[string]*list.Element), order: list.New()}
+	c.Configure(cfg)
+	return c
+}
+
+// Configure меняет TTL и лимит записей на лету; лишние записи вытесняются сразу.
+func (c *LRUCache) Configure(cfg config.CacheConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = time.Duration(cfg.TTL) * time.Minute
+	c.maxEntries = cfg.MaxEntries
+	c.trim()
+}
+
+func (c *LRUCache) Get(ctx context.Context, key string) (*storage.Document, bool) {
+	_, span := tracing.Start(ctx, "LRUCache.Get", attribute.String("cache.key", key))
+	defer span.End()
+
+	doc, ok := c.get(key)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	return doc, ok
+}
+
+func (c *LRUCache) get(key string) (*storage.Document, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	item := el.Value.(*lruEntry).item
+	now := time.Now()
+	if now.After(item.expiresAt) || (c.maxAge > 0 && now.Sub(item.storedAt) > c.maxAge) {
+		c.remove(el)
+		c.evictions++
+		c.misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	c.hits++
+	return item.value, true
+}
+
+func (c *LRUCache) Set(ctx context.Context, key string, doc *storage.Document) {
+	_, span := tracing.Start(ctx, "LRUCache.Set", attribute.String("cache.key", key))
+	defer span.End()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, doc)
+}
+
+func (c *LRUCache) SetLoaded(ctx context.Context, key string, doc *storage.Document, loadedAt time.Time) {
+	_, span := tracing.Start(ctx, "LRUCache.Set", attribute.String("cache.key", key))
+	defer span.End()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.invalidations.since(key, loadedAt) {
+		return
+	}
+	c.set(key, doc)
+}
+
+func (c *LRUCache) set(key string, doc *storage.Document) {
+	now := time.Now()
+	item := cacheItem{value: doc, storedAt: now, expiresAt: now.Add(c.ttl)}
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry).item = item
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, item: item})
+	c.trim()
+}
+
+func (c *LRUCache) Delete(ctx context.Context, key string) {
+	_, span := tracing.Start(ctx, "LRUCache.Delete", attribute.String("cache.key", key))
+	defer span.End()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidations.delete(key)
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+func (c *LRUCache) invalidatedSince(key string, t time.Time) bool {
+	return c.invalidations.since(key, t)
+}
+
+// LimitTTL временно считает истекшими записи старше d; 0 снимает ограничение.
+func (c *LRUCache) LimitTTL(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxAge = d
+}
+
+// Clear удаляет все записи.
+func (c *LRUCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidations.clear()
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+func (c *LRUCache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Evictions:  c.evictions,
+		Size:       int64(c.order.Len()),
+		MaxEntries: c.maxEntries,
+	}
+}
+
+// trim вытесняет давно не читавшиеся записи сверх maxEntries.
+func (c *LRUCache) trim() {
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+func (c *LRUCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*lruEntry).key)
+}
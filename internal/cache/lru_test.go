@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"document-server/internal/config"
+	"fmt"
+	"testing"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(config.CacheConfig{TTL: 5, MaxEntries: 3})
+	for i := 1; i <= 3; i++ {
+		c.Set(ctx, fmt.Sprintf("document:%d", i), testDocument())
+	}
+
+	// После чтения document:1 дольше всех не использовался document:2.
+	if _, ok := c.Get(ctx, "document:1"); !ok {
+		t.Fatal("miss before the cache was full")
+	}
+	c.Set(ctx, "document:4", testDocument())
+	// Перезапись document:3 тоже считается использованием, следующим уходит document:1.
+	c.Set(ctx, "document:3", testDocument())
+	c.Set(ctx, "document:5", testDocument())
+
+	for key, want := range map[string]bool{
+		"document:1": false,
+		"document:2": false,
+		"document:3": true,
+		"document:4": true,
+		"document:5": true,
+	} {
+		if _, ok := c.Get(ctx, key); ok != want {
+			t.Errorf("%s cached = %v, want %v", key, ok, want)
+		}
+	}
+	if stats := c.Stats(); stats.Size != 3 || stats.Evictions != 2 {
+		t.Errorf("stats = %+v, want 3 entries and 2 evictions", stats)
+	}
+}
+
+func TestLRUCacheConfigureShrinks(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(config.CacheConfig{TTL: 5, MaxEntries: 3})
+	for i := 1; i <= 3; i++ {
+		c.Set(ctx, fmt.Sprintf("document:%d", i), testDocument())
+	}
+
+	c.Configure(config.CacheConfig{TTL: 5, MaxEntries: 1})
+	if _, ok := c.Get(ctx, "document:3"); !ok {
+		t.Error("the most recent entry was evicted")
+	}
+	if stats := c.Stats(); stats.Size != 1 {
+		t.Errorf("size = %d after lowering max_entries to 1", stats.Size)
+	}
+}
@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// respClient - клиент RESP2 с пулом не больше poolSize соединений.
+type respClient struct {
+	address  string
+	password string
+	db       int
+	timeout  time.Duration
+	pool     chan *respConn
+	slots    chan struct{}
+}
+
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// respError - ответ сервера с ошибкой; соединение после него остается рабочим.
+type respError string
+
+func (e respError) Error() string {
+	return "resp: " + string(e)
+}
+
+func newRESPClient(address, password string, db int, timeout time.Duration, poolSize int) *respClient {
+	return &respClient{
+		address:  address,
+		password: password,
+		db:       db,
+		timeout:  timeout,
+		pool:     make(chan *respConn, poolSize),
+		slots:    make(chan struct{}, poolSize),
+	}
+}
+
+// Do возвращает string, int64, []byte, []any или nil.
+func (c *respClient) Do(ctx context.Context, args ...string) (any, error) {
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer func() { <-c.slots }()
+
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, c.timeout, args)
+	var replyErr respError
+	if err != nil && !errors.As(err, &replyErr) {
+		// После сетевой ошибки или обрыва посреди ответа соединение не годится.
+		conn.conn.Close()
+		return nil, err
+	}
+	c.put(conn)
+	return reply, err
+}
+
+func (c *respClient) Close() {
+	for {
+		select {
+		case conn := <-c.pool:
+			conn.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// acquire ждет не дольше timeout: при перегрузке быстрее сходить в базу.
+func (c *respClient) acquire(ctx context.Context) error {
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return errors.New("resp: all connections are busy")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *respClient) get(ctx context.Context) (*respConn, error) {
+	select {
+	case conn := <-c.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, err
+	}
+	conn := &respConn{conn: netConn, r: bufio.NewReader(netConn), w: bufio.NewWriter(netConn)}
+
+	if c.password != "" {
+		if _, err := conn.do(ctx, c.timeout, []string{"AUTH", c.password}); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+	}
+	if c.db != 0 {
+		if _, err := conn.do(ctx, c.timeout, []string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("select db: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// put возвращает соединение в пул; лишние закрываются.
+func (c *respClient) put(conn *respConn) {
+	select {
+	case c.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+func (c *respConn) do(ctx context.Context, timeout time.Duration, args []string) (any, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return readRESPReply(c.r)
+}
+
+func readRESPReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("resp: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, respError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("resp: malformed bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("resp: malformed array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readRESPReply(r); err != nil {
+				// Остаток массива не прочитан, поэтому это не respError: соединение закроется.
+				return nil, fmt.Errorf("resp: array item: %v", err)
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("resp: unknown reply type %q", kind)
+	}
+}
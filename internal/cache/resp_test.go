@@ -0,0 +1,282 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// respServer: hook может подменить ответ или оборвать соединение.
+type respServer struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	ttls     map[string]string
+	commands [][]string
+	hook     func(args []string) (reply string, drop bool)
+
+	conns atomic.Int64
+}
+
+func newRESPServer(t *testing.T, password string) *respServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &respServer{
+		listener: listener,
+		password: password,
+		values:   make(map[string]string),
+		ttls:     make(map[string]string),
+	}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *respServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *respServer) setHook(hook func(args []string) (string, bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hook = hook
+}
+
+// Commands возвращает полученные команды, только имена и аргументы.
+func (s *respServer) Commands() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
+
+func (s *respServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.conns.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *respServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		reply, err := readRESPReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+
+		s.mu.Lock()
+		s.commands = append(s.commands, args)
+		hook := s.hook
+		s.mu.Unlock()
+
+		if hook != nil {
+			if out, drop := hook(args); drop {
+				return
+			} else if out != "" {
+				fmt.Fprint(conn, out)
+				continue
+			}
+		}
+
+		var out string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH":
+			if args[1] == s.password {
+				authed = true
+				out = "+OK\r\n"
+			} else {
+				out = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			out = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SELECT", cmd == "PING":
+			out = "+OK\r\n"
+		case cmd == "GET":
+			s.mu.Lock()
+			v, ok := s.values[args[1]]
+			s.mu.Unlock()
+			if ok {
+				out = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			} else {
+				out = "$-1\r\n"
+			}
+		case cmd == "SET":
+			s.mu.Lock()
+			s.values[args[1]] = args[2]
+			if len(args) == 5 && strings.EqualFold(args[3], "PX") {
+				s.ttls[args[1]] = args[4]
+			}
+			s.mu.Unlock()
+			out = "+OK\r\n"
+		case cmd == "DEL":
+			s.mu.Lock()
+			_, ok := s.values[args[1]]
+			delete(s.values, args[1])
+			s.mu.Unlock()
+			if ok {
+				out = ":1\r\n"
+			} else {
+				out = ":0\r\n"
+			}
+		default:
+			out = "-ERR unknown command\r\n"
+		}
+		fmt.Fprint(conn, out)
+	}
+}
+
+func TestRESPHandshake(t *testing.T) {
+	server := newRESPServer(t, "secret")
+	client := newRESPClient(server.Addr(), "secret", 3, time.Second, 2)
+	defer client.Close()
+
+	if _, err := client.Do(context.Background(), "PING"); err != nil {
+		t.Fatalf("PING: %v", err)
+	}
+
+	want := [][]string{{"AUTH", "secret"}, {"SELECT", "3"}, {"PING"}}
+	if got := server.Commands(); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestRESPHandshakeWrongPassword(t *testing.T) {
+	server := newRESPServer(t, "secret")
+	client := newRESPClient(server.Addr(), "wrong", 0, time.Second, 2)
+	defer client.Close()
+
+	_, err := client.Do(context.Background(), "PING")
+	var replyErr respError
+	if !errors.As(err, &replyErr) || !strings.HasPrefix(err.Error(), "auth:") {
+		t.Fatalf("got error %v, want auth reply error", err)
+	}
+	if len(client.pool) != 0 {
+		t.Error("connection that failed AUTH was put into the pool")
+	}
+}
+
+func TestRESPErrorKeepsConnection(t *testing.T) {
+	server := newRESPServer(t, "")
+	server.setHook(func(args []string) (string, bool) {
+		if args[0] == "GET" {
+			return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", false
+		}
+		return "", false
+	})
+	client := newRESPClient(server.Addr(), "", 0, time.Second, 2)
+	defer client.Close()
+
+	for range 3 {
+		_, err := client.Do(context.Background(), "GET", "key")
+		var replyErr respError
+		if !errors.As(err, &replyErr) {
+			t.Fatalf("got error %v, want reply error", err)
+		}
+	}
+	if n := server.conns.Load(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}
+
+func TestRESPNetworkErrorDropsConnection(t *testing.T) {
+	server := newRESPServer(t, "")
+	var dropped atomic.Bool
+	server.setHook(func(args []string) (string, bool) {
+		// Первый GET обрывает соединение, не ответив.
+		return "", args[0] == "GET" && dropped.CompareAndSwap(false, true)
+	})
+	client := newRESPClient(server.Addr(), "", 0, time.Second, 2)
+	defer client.Close()
+
+	if _, err := client.Do(context.Background(), "GET", "key"); err == nil {
+		t.Fatal("expected an error from a dropped connection")
+	}
+	if len(client.pool) != 0 {
+		t.Fatal("broken connection was put into the pool")
+	}
+	if _, err := client.Do(context.Background(), "GET", "key"); err != nil {
+		t.Fatalf("GET after reconnect: %v", err)
+	}
+	if n := server.conns.Load(); n != 2 {
+		t.Errorf("dialed %d connections, want 2", n)
+	}
+}
+
+func TestRESPPoolBoundsConnections(t *testing.T) {
+	server := newRESPServer(t, "")
+	server.setHook(func(args []string) (string, bool) {
+		time.Sleep(20 * time.Millisecond)
+		return "", false
+	})
+	client := newRESPClient(server.Addr(), "", 0, time.Second, 2)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Do(context.Background(), "PING"); err != nil {
+				t.Errorf("PING: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := server.conns.Load(); n > 2 {
+		t.Errorf("dialed %d connections with pool_size 2", n)
+	}
+}
+
+func TestRESPPoolWaitHonorsContext(t *testing.T) {
+	server := newRESPServer(t, "")
+	release := make(chan struct{})
+	server.setHook(func(args []string) (string, bool) {
+		<-release
+		return "", false
+	})
+	defer close(release)
+	client := newRESPClient(server.Addr(), "", 0, time.Second, 1)
+	defer client.Close()
+
+	go client.Do(context.Background(), "PING")
+	// Ждем, пока первая команда займет единственное место.
+	for len(client.slots) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := client.Do(ctx, "PING"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v while the only connection is busy, want deadline exceeded", err)
+	}
+	if n := server.conns.Load(); n != 1 {
+		t.Errorf("dialed %d connections with pool_size 1", n)
+	}
+}
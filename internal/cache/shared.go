@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"document-server/internal/config"
+	"document-server/internal/logger"
+	storage "document-server/internal/storage/document"
+	"document-server/internal/tracing"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SharedCache при недоступном сервере считает чтение промахом.
+type SharedCache struct {
+	client *respClient
+	prefix string
+	ttl    time.Duration
+	logger *slog.Logger
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+}
+
+func NewSharedCache(cfg config.SharedCacheConfig, logger *slog.Logger) *SharedCache {
+	timeout := time.Duration(cfg.TimeoutMS) * time.Millisecond
+	return &SharedCache{
+		client: newRESPClient(cfg.Address, cfg.Password, cfg.DB, timeout, cfg.PoolSize),
+		prefix: cfg.KeyPrefix,
+		ttl:    time.Duration(cfg.TTL) * time.Second,
+		logger: logger,
+	}
+}
+
+func (c *SharedCache) Get(ctx context.Context, key string) (*storage.Document, bool) {
+	ctx, span := tracing.Start(ctx, "SharedCache.Get", attribute.String("cache.key", key))
+	defer span.End()
+
+	doc, ok := c.get(ctx, key)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	return doc, ok
+}
+
+func (c *SharedCache) get(ctx context.Context, key string) (*storage.Document, bool) {
+	reply, err := c.client.Do(ctx, "GET", c.prefix+key)
+	if err != nil {
+		c.fail(ctx, "get", key, err)
+		c.misses.Add(1)
+		return nil, false
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	var doc storage.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		// Запись старого формата или чужая: считаем промахом, Set ее перезапишет.
+		c.fail(ctx, "decode", key, err)
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return &doc, true
+}
+
+func (c *SharedCache) Set(ctx context.Context, key string, doc *storage.Document) {
+	ctx, span := tracing.Start(ctx, "SharedCache.Set", attribute.String("cache.key", key))
+	defer span.End()
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		c.fail(ctx, "encode", key, err)
+		return
+	}
+	if _, err := c.client.Do(ctx, "SET", c.prefix+key, string(data), "PX", strconv.FormatInt(c.ttl.Milliseconds(), 10)); err != nil {
+		c.fail(ctx, "set", key, err)
+	}
+}
+
+// Delete после неудачи не повторяется: устаревшая запись проживет до ttl.
+func (c *SharedCache) Delete(ctx context.Context, key string) {
+	ctx, span := tracing.Start(ctx, "SharedCache.Delete", attribute.String("cache.key", key))
+	defer span.End()
+
+	if _, err := c.client.Do(ctx, "DEL", c.prefix+key); err != nil {
+		c.fail(ctx, "delete", key, err)
+	}
+}
+
+func (c *SharedCache) Ping(ctx context.Context) error {
+	_, err := c.client.Do(ctx, "PING")
+	return err
+}
+
+// Stats возвращает счетчики этого экземпляра; размер общего кэша не считается.
+func (c *SharedCache) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Errors: c.errors.Load(),
+	}
+}
+
+func (c *SharedCache) Close() {
+	c.client.Close()
+}
+
+func (c *SharedCache) fail(ctx context.Context, op, key string, err error) {
+	c.errors.Add(1)
+	logger.FromContext(ctx, c.logger).Warn("shared cache operation failed",
+		slog.String("op", op), slog.String("key", key), slog.String("error", err.Error()))
+}
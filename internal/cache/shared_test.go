@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/config"
+	storage "document-server/internal/storage/document"
+	"io"
+	"log/slog"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func newTestSharedCache(t *testing.T, server *respServer) *SharedCache {
+	t.Helper()
+	c := NewSharedCache(config.SharedCacheConfig{
+		Address:   server.Addr(),
+		KeyPrefix: "test:",
+		TTL:       90,
+		TimeoutMS: 1000,
+		PoolSize:  2,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func testDocument() *storage.Document {
+	created := time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC)
+	return &storage.Document{
+		ID:              uuid.New(),
+		Name:            "report.pdf",
+		MimeType:        "application/pdf",
+		IsFile:          true,
+		FilePath:        sql.NullString{String: "/uploads/report", Valid: true},
+		CreatedAt:       created,
+		GrantedTo:       pq.StringArray{"alice", "bob"},
+		OriginalName:    sql.NullString{String: "Report.pdf", Valid: true},
+		SHA256:          sql.NullString{String: "abc", Valid: true},
+		KeyID:           sql.NullString{String: "k1", Valid: true},
+		WrappedKey:      []byte{0, 1, 2, 0xff},
+		Size:            sql.NullInt64{Int64: 42, Valid: true},
+		StoredSize:      sql.NullInt64{Int64: 30, Valid: true},
+		ContentEncoding: sql.NullString{String: "gzip", Valid: true},
+		OwnerID:         uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		ExpiresAt:       sql.NullTime{Time: created.Add(time.Hour), Valid: true},
+	}
+}
+
+func TestSharedCacheRoundTrip(t *testing.T) {
+	server := newRESPServer(t, "")
+	c := newTestSharedCache(t, server)
+	ctx := context.Background()
+
+	for _, doc := range []*storage.Document{testDocument(), {ID: uuid.New(), Name: "empty"}} {
+		key := "document:" + doc.ID.String()
+		c.Set(ctx, key, doc)
+
+		got, ok := c.Get(ctx, key)
+		if !ok {
+			t.Fatalf("%s: miss after Set", doc.Name)
+		}
+		if !reflect.DeepEqual(got, doc) {
+			t.Errorf("%s: round trip\n got %+v\nwant %+v", doc.Name, got, doc)
+		}
+	}
+	if stats := c.Stats(); stats.Hits != 2 || stats.Errors != 0 {
+		t.Errorf("stats = %+v, want 2 hits and no errors", stats)
+	}
+}
+
+func TestSharedCacheKeyPrefixAndTTL(t *testing.T) {
+	server := newRESPServer(t, "")
+	c := newTestSharedCache(t, server)
+	ctx := context.Background()
+
+	c.Set(ctx, "document:1", testDocument())
+
+	server.mu.Lock()
+	_, stored := server.values["test:document:1"]
+	ttl := server.ttls["test:document:1"]
+	server.mu.Unlock()
+	if !stored {
+		t.Fatal("document is not stored under the key prefix")
+	}
+	if ttl != "90000" {
+		t.Errorf("PX = %q, want 90000", ttl)
+	}
+
+	c.Delete(ctx, "document:1")
+	if _, ok := c.Get(ctx, "document:1"); ok {
+		t.Error("hit after Delete")
+	}
+}
+
+func TestSharedCacheUnreachableIsMiss(t *testing.T) {
+	server := newRESPServer(t, "")
+	c := newTestSharedCache(t, server)
+	server.listener.Close()
+
+	if _, ok := c.Get(context.Background(), "document:1"); ok {
+		t.Fatal("hit from an unreachable server")
+	}
+	if stats := c.Stats(); stats.Errors != 1 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, want one error counted as a miss", stats)
+	}
+}
+
+func TestTieredCacheReadThrough(t *testing.T) {
+	server := newRESPServer(t, "")
+	shared := newTestSharedCache(t, server)
+	local := NewLRUCache(config.CacheConfig{TTL: 5, MaxEntries: 10})
+	c := NewTieredCache(local, shared)
+	ctx := context.Background()
+
+	doc := testDocument()
+	shared.Set(ctx, "document:1", doc)
+
+	if _, ok := c.Get(ctx, "document:1"); !ok {
+		t.Fatal("miss although the shared cache has the document")
+	}
+	if _, ok := local.Get(ctx, "document:1"); !ok {
+		t.Fatal("read from the shared cache did not fill the local one")
+	}
+
+	// Второе чтение обслуживает локальный кэш, не обращаясь к общему.
+	hits := shared.Stats().Hits
+	if _, ok := c.Get(ctx, "document:1"); !ok {
+		t.Fatal("miss on the second read")
+	}
+	if shared.Stats().Hits != hits {
+		t.Error("second read went to the shared cache")
+	}
+}
+
+func TestTieredCacheDeletesSharedFirst(t *testing.T) {
+	server := newRESPServer(t, "")
+	shared := newTestSharedCache(t, server)
+	local := NewLRUCache(config.CacheConfig{TTL: 5, MaxEntries: 10})
+	c := NewTieredCache(local, shared)
+	ctx := context.Background()
+
+	c.Set(ctx, "document:1", testDocument())
+
+	var localAtDel atomic.Bool
+	server.setHook(func(args []string) (string, bool) {
+		if args[0] == "DEL" {
+			local.mu.Lock()
+			_, ok := local.items["document:1"]
+			local.mu.Unlock()
+			localAtDel.Store(ok)
+		}
+		return "", false
+	})
+	c.Delete(ctx, "document:1")
+
+	if !localAtDel.Load() {
+		t.Error("local entry was removed before the shared one")
+	}
+	if _, ok := c.Get(ctx, "document:1"); ok {
+		t.Error("hit after Delete")
+	}
+}
+
+func TestTieredCacheStats(t *testing.T) {
+	server := newRESPServer(t, "")
+	shared := newTestSharedCache(t, server)
+	local := NewLRUCache(config.CacheConfig{TTL: 5, MaxEntries: 10})
+	c := NewTieredCache(local, shared)
+	ctx := context.Background()
+
+	shared.Set(ctx, "document:1", testDocument())
+	c.Get(ctx, "document:1") // попадание в общий
+	c.Get(ctx, "document:1") // попадание в локальный
+	c.Get(ctx, "document:2") // промах обоих
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Size != 1 || stats.MaxEntries != 10 {
+		t.Errorf("stats = %+v, want 2 hits, 1 miss and one local entry", stats)
+	}
+}
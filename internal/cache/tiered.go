@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"document-server/internal/config"
+	storage "document-server/internal/storage/document"
+	"time"
+)
+
+// Local - InMemoryCache или LRUCache.
+type Local interface {
+	Get(ctx context.Context, key string) (*storage.Document, bool)
+	Set(ctx context.Context, key string, doc *storage.Document)
+	SetLoaded(ctx context.Context, key string, doc *storage.Document, loadedAt time.Time)
+	Delete(ctx context.Context, key string)
+	Configure(cfg config.CacheConfig)
+	LimitTTL(d time.Duration)
+	Clear()
+	Stats() Stats
+}
+
+// TieredCache без cache.listen может отдавать локальную запись устаревшей до cache.ttl.
+type TieredCache struct {
+	local  *LRUCache
+	shared *SharedCache
+}
+
+func NewTieredCache(local *LRUCache, shared *SharedCache) *TieredCache {
+	return &TieredCache{local: local, shared: shared}
+}
+
+func (c *TieredCache) Get(ctx context.Context, key string) (*storage.Document, bool) {
+	if doc, ok := c.local.Get(ctx, key); ok {
+		return doc, true
+	}
+	loadedAt := time.Now()
+	doc, ok := c.shared.Get(ctx, key)
+	if ok {
+		c.local.SetLoaded(ctx, key, doc, loadedAt)
+	}
+	return doc, ok
+}
+
+func (c *TieredCache) Set(ctx context.Context, key string, doc *storage.Document) {
+	c.shared.Set(ctx, key, doc)
+	c.local.Set(ctx, key, doc)
+}
+
+func (c *TieredCache) SetLoaded(ctx context.Context, key string, doc *storage.Document, loadedAt time.Time) {
+	if c.local.invalidatedSince(key, loadedAt) {
+		return
+	}
+	c.shared.Set(ctx, key, doc)
+	c.local.SetLoaded(ctx, key, doc, loadedAt)
+}
+
+// Stats: промахи - общего кэша, размер и вытеснения - локального.
+func (c *TieredCache) Stats() Stats {
+	local, shared := c.local.Stats(), c.shared.Stats()
+	return Stats{
+		Hits:       local.Hits + shared.Hits,
+		Misses:     shared.Misses,
+		Evictions:  local.Evictions,
+		Size:       local.Size,
+		MaxEntries: local.MaxEntries,
+		Errors:     shared.Errors,
+	}
+}
+
+// Delete идет сначала в общий кэш, иначе чтение вернет старую запись в локальный.
+func (c *TieredCache) Delete(ctx context.Context, key string) {
+	c.shared.Delete(ctx, key)
+	c.local.Delete(ctx, key)
+}
@@ -2,16 +2,33 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 )
 
 type Config struct {
-	Server      ServerConfig      `json:"server"`
-	Database    DatabaseConfig    `json:"database"`
-	AdminToken  string            `json:"adminToken"`
-	CacheConfig CacheConfig       `json:"cache"`
-	Log         LogConfig         `json:"log"`
-	FileStorage FileStorageConfig `json:"fileStorage"`
+	Server       ServerConfig       `json:"server"`
+	Database     DatabaseConfig     `json:"database"`
+	AdminToken   string             `json:"adminToken"`
+	CacheConfig  CacheConfig        `json:"cache"`
+	Log          LogConfig          `json:"log"`
+	FileStorage  FileStorageConfig  `json:"fileStorage"`
+	Auth         AuthConfig         `json:"auth"`
+	OIDC         OIDCConfig         `json:"oidc"`
+	TokenJanitor TokenJanitorConfig `json:"tokenJanitor"`
+	Tracing      TracingConfig      `json:"tracing"`
+	Health       HealthConfig       `json:"health"`
+	RateLimit    RateLimitConfig    `json:"rateLimit"`
+	Upload       UploadConfig       `json:"upload"`
+	Reconciler   ReconcilerConfig   `json:"reconciler"`
+	MIMEPolicy   MIMEPolicyConfig   `json:"mimePolicy"`
+	Integrity    IntegrityConfig    `json:"integrity"`
+	Encryption   EncryptionConfig   `json:"encryption"`
+	Compression  CompressionConfig  `json:"compression"`
+	Quota        QuotaConfig        `json:"quota"`
+	Retention    RetentionConfig    `json:"retention"`
+	Webhooks     WebhooksConfig     `json:"webhooks"`
+	Events       EventsConfig       `json:"events"`
 }
 
 type ServerConfig struct {
@@ -22,9 +39,25 @@ type LogConfig struct {
 	Level string `json:"level"`
 }
 
+// CacheConfig: ttl и fallback_ttl - в секундах; backend - memory, shared или tiered.
 type CacheConfig struct {
-	TTL        int `json:"ttl"`
-	MaxEntries int `json:"max_entries"`
+	TTL         int               `json:"ttl"`
+	MaxEntries  int               `json:"max_entries"`
+	Listen      bool              `json:"listen"`
+	FallbackTTL int               `json:"fallback_ttl"`
+	Backend     string            `json:"backend"`
+	Shared      SharedCacheConfig `json:"shared"`
+}
+
+// SharedCacheConfig: ttl - в секундах, timeout_ms - на одну команду вместе с подключением.
+type SharedCacheConfig struct {
+	Address   string `json:"address"`
+	Password  string `json:"password"`
+	DB        int    `json:"db"`
+	KeyPrefix string `json:"key_prefix"`
+	TTL       int    `json:"ttl"`
+	TimeoutMS int    `json:"timeout_ms"`
+	PoolSize  int    `json:"pool_size"`
 }
 
 type DatabaseConfig struct {
@@ -37,23 +70,190 @@ type DatabaseConfig struct {
 	MaxOpenConns    int    `json:"maxOpenConns"`
 	MaxIdleConns    int    `json:"maxIdleConns"`
 	ConnMaxLifetime int    `json:"connMaxLifetime"`
+	AutoMigrate     bool   `json:"autoMigrate"`
 }
 
 type FileStorageConfig struct {
 	Path string `json:"path"`
 }
 
-func LoadConfig(path string) (*Config, error) {
+// AuthConfig: обе проверки по умолчанию выключены.
+type AuthConfig struct {
+	SessionExpiry  bool `json:"sessionExpiry"`
+	DocumentAccess bool `json:"documentAccess"`
+}
+
+type OIDCConfig struct {
+	Enabled             bool   `json:"enabled"`
+	Issuer              string `json:"issuer"`
+	Audience            string `json:"audience"`
+	JWKSFile            string `json:"jwksFile"`
+	JWKSURL             string `json:"jwksUrl"`
+	JWKSRefreshInterval int    `json:"jwksRefreshInterval"`
+	ClockSkew           int    `json:"clockSkew"`
+	LoginClaim          string `json:"loginClaim"`
+	AutoProvision       bool   `json:"autoProvision"`
+}
+
+type TokenJanitorConfig struct {
+	Interval int `json:"interval"`
+}
+
+// TracingConfig: sampleRatio - доля корневых трасс, от 0 до 1.
+type TracingConfig struct {
+	Exporter    string  `json:"exporter"`
+	Endpoint    string  `json:"endpoint"`
+	Insecure    bool    `json:"insecure"`
+	FilePath    string  `json:"filePath"`
+	ServiceName string  `json:"serviceName"`
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+// HealthConfig: drainDelay - секунды отказа /readyz перед остановкой.
+type HealthConfig struct {
+	CheckTimeout int `json:"checkTimeout"`
+	DrainDelay   int `json:"drainDelay"`
+}
+
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	Burst             int     `json:"burst"`
+}
+
+type UploadConfig struct {
+	MaxSize         int `json:"maxSize"`
+	MultipartMemory int `json:"multipartMemory"`
+}
+
+// ReconcilerConfig: interval и gracePeriod - в минутах.
+type ReconcilerConfig struct {
+	Interval       int    `json:"interval"`
+	GracePeriod    int    `json:"gracePeriod"`
+	Action         string `json:"action"`
+	QuarantinePath string `json:"quarantinePath"`
+}
+
+// MIMEPolicyConfig: пустой allow разрешает все, что не в deny.
+type MIMEPolicyConfig struct {
+	User  MIMERulesConfig `json:"user"`
+	Admin MIMERulesConfig `json:"admin"`
+}
+
+type MIMERulesConfig struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+type IntegrityConfig struct {
+	MD5          bool `json:"md5"`
+	VerifyOnRead bool `json:"verifyOnRead"`
+}
+
+// EncryptionConfig: ключи - 32 байта в base64, keyFile - JSON {"id": "base64"}.
+type EncryptionConfig struct {
+	Enabled   bool   `json:"enabled"`
+	KeyID     string `json:"keyId"`
+	MasterKey string `json:"masterKey"`
+	KeyFile   string `json:"keyFile"`
+}
+
+type CompressionConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Level     int      `json:"level"`
+	MinSize   int      `json:"minSize"`
+	MIMETypes []string `json:"mimeTypes"`
+}
+
+// QuotaConfig: 0 - без ограничения.
+type QuotaConfig struct {
+	MaxBytes          int `json:"maxBytes"`
+	MaxDocuments      int `json:"maxDocuments"`
+	GroupMaxBytes     int `json:"groupMaxBytes"`
+	GroupMaxDocuments int `json:"groupMaxDocuments"`
+}
+
+// RetentionConfig: interval - в минутах; нулевое значение выключает соответствующую очистку.
+type RetentionConfig struct {
+	Interval  int `json:"interval"`
+	BatchSize int `json:"batchSize"`
+	TrashDays int `json:"trashDays"`
+	EventDays int `json:"eventDays"`
+}
+
+// WebhooksConfig: время - в секундах, interval 0 выключает отправку.
+type WebhooksConfig struct {
+	Interval     int  `json:"interval"`
+	BatchSize    int  `json:"batchSize"`
+	Timeout      int  `json:"timeout"`
+	MaxAttempts  int  `json:"maxAttempts"`
+	BackoffBase  int  `json:"backoffBase"`
+	BackoffMax   int  `json:"backoffMax"`
+	AllowPrivate bool `json:"allowPrivate"`
+}
+
+// EventsConfig: heartbeat - в секундах.
+type EventsConfig struct {
+	History   int `json:"history"`
+	Heartbeat int `json:"heartbeat"`
+}
+
+// Default возвращает конфигурацию, поверх которой накладываются файл, переменные окружения и флаги.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{Address: ":8080"},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            "5432",
+			User:            "postgres",
+			Name:            "docs_db",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 600,
+		},
+		CacheConfig: CacheConfig{
+			TTL: 5, MaxEntries: 1000, Listen: true, FallbackTTL: 5, Backend: "memory",
+			Shared: SharedCacheConfig{Address: "localhost:6379", KeyPrefix: "docsrv:", TTL: 300, TimeoutMS: 200, PoolSize: 10},
+		},
+		Log:         LogConfig{Level: "info"},
+		FileStorage: FileStorageConfig{Path: "./uploads"},
+		OIDC: OIDCConfig{
+			JWKSRefreshInterval: 3600,
+			ClockSkew:           30,
+			LoginClaim:          "preferred_username",
+			AutoProvision:       true,
+		},
+		TokenJanitor: TokenJanitorConfig{Interval: 60},
+		Tracing:      TracingConfig{Exporter: "none", ServiceName: "document-server", SampleRatio: 1},
+		Health:       HealthConfig{CheckTimeout: 2, DrainDelay: 5},
+		Upload:       UploadConfig{MaxSize: 100 << 20, MultipartMemory: 32 << 20},
+		Reconciler:   ReconcilerConfig{Interval: 360, GracePeriod: 60, Action: "report"},
+		Retention:    RetentionConfig{Interval: 10, BatchSize: 100, TrashDays: 30, EventDays: 7},
+		Webhooks:     WebhooksConfig{Interval: 5, BatchSize: 100, Timeout: 10, MaxAttempts: 8, BackoffBase: 30, BackoffMax: 3600},
+		Events:       EventsConfig{History: 1000, Heartbeat: 15},
+		MIMEPolicy: MIMEPolicyConfig{
+			User: MIMERulesConfig{Allow: []string{}, Deny: []string{
+				"text/html", "application/xhtml+xml", "image/svg+xml",
+				"text/javascript", "application/javascript", "application/x-msdownload",
+			}},
+			Admin: MIMERulesConfig{Allow: []string{}, Deny: []string{}},
+		},
+		Compression: CompressionConfig{Level: 6, MinSize: 1024, MIMETypes: []string{
+			"text/*", "application/json", "application/xml", "application/x-ndjson", "application/x-yaml",
+		}},
+	}
+}
+
+func loadFile(path string, cfg *Config) error {
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer file.Close()
 
-	var cfg Config
 	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&cfg); err != nil {
-		return nil, err
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
 	}
-	return &cfg, nil
+	return nil
 }
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	envPrefix         = "DOCSRV_"
+	defaultConfigPath = "configs/config.json"
+)
+
+// Load накладывает по порядку: значения по умолчанию, JSON файл, DOCSRV_* и флаги.
+func Load(name string, args []string) (*Config, []string, error) {
+	fset := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	configPath := fset.String("config", "", "path to JSON config file")
+	overrides := map[string]string{}
+
+	cfg := Default()
+	walkFields(reflect.ValueOf(&cfg).Elem(), nil, func(path []string, _ reflect.Value) {
+		key := strings.Join(path, ".")
+		fset.Func(key, "override "+key, func(v string) error {
+			overrides[key] = v
+			return nil
+		})
+	})
+
+	if err := fset.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	path, explicit := *configPath, true
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+	}
+	if path == "" {
+		path, explicit = defaultConfigPath, false
+	}
+	if err := loadFile(path, &cfg); err != nil {
+		if explicit || !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, err
+		}
+	}
+
+	var errs []error
+	walkFields(reflect.ValueOf(&cfg).Elem(), nil, func(path []string, field reflect.Value) {
+		name := envName(path)
+		raw, ok, err := lookupEnv(name)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if ok {
+			if err := setField(field, raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+
+		key := strings.Join(path, ".")
+		if raw, ok := overrides[key]; ok {
+			if err := setField(field, raw); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", key, err))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	return &cfg, fset.Args(), nil
+}
+
+func lookupEnv(name string) (string, bool, error) {
+	if file, ok := os.LookupEnv(name + "_FILE"); ok {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+	raw, ok := os.LookupEnv(name)
+	return raw, ok, nil
+}
+
+func walkFields(v reflect.Value, path []string, fn func(path []string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fieldPath := append(append([]string{}, path...), tag)
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			walkFields(field, fieldPath, fn)
+			continue
+		}
+		fn(fieldPath, field)
+	}
+}
+
+func setField(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		field.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", field.Type())
+		}
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Kind())
+	}
+	return nil
+}
+
+// envName переводит путь JSON тегов в имя переменной: fileStorage.path -> DOCSRV_FILE_STORAGE_PATH.
+func envName(path []string) string {
+	parts := make([]string, 0, len(path))
+	for _, p := range path {
+		var b strings.Builder
+		for i, r := range p {
+			if unicode.IsUpper(r) && i > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		parts = append(parts, b.String())
+	}
+	return envPrefix + strings.Join(parts, "_")
+}
+
+// Diff возвращает JSON пути полей, значения которых отличаются в a и b.
+func Diff(a, b *Config) []string {
+	var left, right []reflect.Value
+	var paths []string
+	walkFields(reflect.ValueOf(a).Elem(), nil, func(path []string, field reflect.Value) {
+		left = append(left, field)
+		paths = append(paths, strings.Join(path, "."))
+	})
+	walkFields(reflect.ValueOf(b).Elem(), nil, func(_ []string, field reflect.Value) {
+		right = append(right, field)
+	})
+
+	changed := []string{}
+	for i := range paths {
+		if !reflect.DeepEqual(left[i].Interface(), right[i].Interface()) {
+			changed = append(changed, paths[i])
+		}
+	}
+	return changed
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	file := `{"database": {"host": "file-host", "port": "6543", "user": "file-user"}, "cache": {"ttl": 30}}`
+	secret := writeFile(t, "password", "from-file\n")
+
+	cases := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		args  []string
+		check func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "defaults",
+			file: `{}`,
+			check: func(t *testing.T, cfg *Config) {
+				def := Default()
+				if cfg.Database != def.Database || cfg.CacheConfig.TTL != def.CacheConfig.TTL {
+					t.Fatalf("got %+v, want defaults %+v", cfg.Database, def.Database)
+				}
+			},
+		},
+		{
+			name: "file over defaults",
+			file: file,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Database.Host != "file-host" || cfg.Database.Port != "6543" || cfg.CacheConfig.TTL != 30 {
+					t.Fatalf("file values not applied: %+v", cfg.Database)
+				}
+				if cfg.Database.Name != Default().Database.Name {
+					t.Fatalf("database.name = %q, want default", cfg.Database.Name)
+				}
+			},
+		},
+		{
+			name: "env over file",
+			file: file,
+			env:  map[string]string{"DOCSRV_DATABASE_PORT": "7000", "DOCSRV_CACHE_MAX_ENTRIES": "50"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Database.Port != "7000" || cfg.CacheConfig.MaxEntries != 50 {
+					t.Fatalf("env values not applied: port %q, max_entries %d", cfg.Database.Port, cfg.CacheConfig.MaxEntries)
+				}
+				if cfg.Database.Host != "file-host" {
+					t.Fatalf("database.host = %q, want file value", cfg.Database.Host)
+				}
+			},
+		},
+		{
+			name: "flags over env",
+			file: file,
+			env:  map[string]string{"DOCSRV_DATABASE_PORT": "7000", "DOCSRV_DATABASE_USER": "env-user"},
+			args: []string{"-database.port=8000", "-mimePolicy.user.deny=text/html, image/svg+xml"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Database.Port != "8000" || cfg.Database.User != "env-user" {
+					t.Fatalf("got port %q user %q, want flag port and env user", cfg.Database.Port, cfg.Database.User)
+				}
+				if want := []string{"text/html", "image/svg+xml"}; !slices.Equal(cfg.MIMEPolicy.User.Deny, want) {
+					t.Fatalf("mimePolicy.user.deny = %v, want %v", cfg.MIMEPolicy.User.Deny, want)
+				}
+			},
+		},
+		{
+			name: "file indirection wins over plain env",
+			file: file,
+			env: map[string]string{
+				"DOCSRV_DATABASE_PASSWORD":      "plain",
+				"DOCSRV_DATABASE_PASSWORD_FILE": secret,
+			},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Database.Password != "from-file" {
+					t.Fatalf("database.password = %q, want trimmed file content", cfg.Database.Password)
+				}
+			},
+		},
+		{
+			name: "flag over file indirection",
+			file: file,
+			env:  map[string]string{"DOCSRV_DATABASE_PASSWORD_FILE": secret},
+			args: []string{"-database.password", "from-flag"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Database.Password != "from-flag" {
+					t.Fatalf("database.password = %q, want flag value", cfg.Database.Password)
+				}
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			args := append([]string{"-config", writeFile(t, "config.json", tc.file)}, tc.args...)
+			args = append(args, "positional")
+
+			cfg, rest, err := Load("test", args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(rest, []string{"positional"}) {
+				t.Fatalf("positional args = %v", rest)
+			}
+			tc.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadConfigPathEnv(t *testing.T) {
+	t.Setenv("CONFIG_PATH", writeFile(t, "config.json", `{"log": {"level": "debug"}}`))
+
+	cfg, _, err := Load("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Log.Level != "debug" {
+		t.Fatalf("log.level = %q, want value from CONFIG_PATH", cfg.Log.Level)
+	}
+
+	t.Setenv("CONFIG_PATH", filepath.Join(t.TempDir(), "missing.json"))
+	if _, _, err := Load("test", nil); err == nil {
+		t.Fatal("missing explicit config file accepted")
+	}
+}
+
+func TestLoadReportsAllInvalidFields(t *testing.T) {
+	t.Setenv("DOCSRV_DATABASE_MAX_OPEN_CONNS", "many")
+	t.Setenv("DOCSRV_LOG_LEVEL_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	_, _, err := Load("test", []string{
+		"-config", writeFile(t, "config.json", `{}`),
+		"-cache.ttl=soon",
+		"-auth.sessionExpiry=maybe",
+	})
+	if err == nil {
+		t.Fatal("invalid values accepted")
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("error %T is not a joined error", err)
+	}
+	if n := len(joined.Unwrap()); n != 4 {
+		t.Fatalf("got %d errors, want 4: %v", n, err)
+	}
+	for _, want := range []string{
+		`DOCSRV_DATABASE_MAX_OPEN_CONNS: invalid integer "many"`,
+		"DOCSRV_LOG_LEVEL_FILE:",
+		`-cache.ttl: invalid integer "soon"`,
+		`-auth.sessionExpiry: invalid boolean "maybe"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
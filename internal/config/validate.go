@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	sslModes       = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	logLevels      = []string{"debug", "info", "warn", "error"}
+	traceExporters = []string{"none", "otlp", "stdout", "file"}
+	reconcileModes = []string{"report", "quarantine"}
+	cacheBackends  = []string{"memory", "shared", "tiered"}
+)
+
+// Validate проверяет всю конфигурацию и возвращает все найденные проблемы сразу.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Address != "", "server.address must not be empty")
+
+	check(c.Database.Host != "", "database.host must not be empty")
+	port, err := strconv.Atoi(c.Database.Port)
+	check(err == nil && port > 0 && port < 65536, "database.port must be a TCP port, got %q", c.Database.Port)
+	check(c.Database.User != "", "database.user must not be empty")
+	check(c.Database.Name != "", "database.name must not be empty")
+	check(slices.Contains(sslModes, c.Database.SSLMode), "database.sslmode must be one of %v, got %q", sslModes, c.Database.SSLMode)
+	check(c.Database.MaxOpenConns >= 0, "database.maxOpenConns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.maxIdleConns must not be negative")
+	check(c.Database.ConnMaxLifetime >= 0, "database.connMaxLifetime must not be negative")
+
+	check(c.CacheConfig.TTL > 0, "cache.ttl must be positive")
+	check(c.CacheConfig.MaxEntries >= 0, "cache.max_entries must not be negative")
+	check(!c.CacheConfig.Listen || c.CacheConfig.FallbackTTL > 0, "cache.fallback_ttl must be positive when cache.listen is set")
+	check(slices.Contains(cacheBackends, c.CacheConfig.Backend), "cache.backend must be one of %v, got %q", cacheBackends, c.CacheConfig.Backend)
+	if c.CacheConfig.Backend != "memory" {
+		shared := c.CacheConfig.Shared
+		check(shared.Address != "", "cache.shared.address must not be empty")
+		check(shared.DB >= 0, "cache.shared.db must not be negative")
+		check(shared.TTL > 0, "cache.shared.ttl must be positive")
+		check(shared.TimeoutMS > 0, "cache.shared.timeout_ms must be positive")
+		check(shared.PoolSize > 0, "cache.shared.pool_size must be positive")
+	}
+
+	check(slices.Contains(logLevels, c.Log.Level), "log.level must be one of %v, got %q", logLevels, c.Log.Level)
+	check(c.FileStorage.Path != "", "fileStorage.path must not be empty")
+
+	if c.OIDC.Enabled {
+		// без iss и aud подошел бы токен, выданный другому клиенту
+		check(c.OIDC.Issuer != "", "oidc.issuer must not be empty")
+		check(c.OIDC.Audience != "", "oidc.audience must not be empty")
+		check((c.OIDC.JWKSFile == "") != (c.OIDC.JWKSURL == ""), "oidc: exactly one of jwksFile and jwksUrl must be set")
+		check(c.OIDC.JWKSURL == "" || c.OIDC.JWKSRefreshInterval > 0, "oidc.jwksRefreshInterval must be positive")
+		check(c.OIDC.ClockSkew >= 0, "oidc.clockSkew must not be negative")
+	}
+
+	check(c.TokenJanitor.Interval >= 0, "tokenJanitor.interval must not be negative")
+
+	check(c.Tracing.Exporter == "" || slices.Contains(traceExporters, c.Tracing.Exporter), "tracing.exporter must be one of %v, got %q", traceExporters, c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "file" || c.Tracing.FilePath != "", "tracing.filePath is required for the file exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio must be between 0 and 1")
+
+	check(c.Health.CheckTimeout > 0, "health.checkTimeout must be positive")
+	check(c.Health.DrainDelay >= 0, "health.drainDelay must not be negative")
+
+	check(c.RateLimit.RequestsPerSecond >= 0, "rateLimit.requestsPerSecond must not be negative")
+	check(c.RateLimit.RequestsPerSecond == 0 || c.RateLimit.Burst > 0, "rateLimit.burst must be positive when rate limiting is enabled")
+
+	check(c.Upload.MaxSize > 0, "upload.maxSize must be positive")
+	check(c.Upload.MultipartMemory > 0, "upload.multipartMemory must be positive")
+
+	check(c.Reconciler.Interval >= 0, "reconciler.interval must not be negative")
+	check(c.Reconciler.GracePeriod >= 0, "reconciler.gracePeriod must not be negative")
+	check(slices.Contains(reconcileModes, c.Reconciler.Action), "reconciler.action must be one of %v, got %q", reconcileModes, c.Reconciler.Action)
+
+	check(!c.Encryption.Enabled || c.Encryption.KeyID != "", "encryption.keyId is required when encryption is enabled")
+	check(!c.Encryption.Enabled || c.Encryption.MasterKey != "" || c.Encryption.KeyFile != "", "encryption.masterKey or encryption.keyFile is required when encryption is enabled")
+
+	check(c.Compression.Level >= 1 && c.Compression.Level <= 9, "compression.level must be between 1 and 9")
+	check(c.Compression.MinSize >= 0 && c.Compression.MinSize <= 8192, "compression.minSize must be between 0 and 8192")
+	for _, pattern := range c.Compression.MIMETypes {
+		check(validMIMEPattern(pattern), "compression: invalid MIME pattern %q", pattern)
+	}
+
+	check(c.Retention.Interval >= 0, "retention.interval must not be negative")
+	check(c.Retention.BatchSize > 0, "retention.batchSize must be positive")
+	check(c.Retention.TrashDays >= 0, "retention.trashDays must not be negative")
+	check(c.Retention.EventDays >= 0, "retention.eventDays must not be negative")
+
+	check(c.Webhooks.Interval >= 0, "webhooks.interval must not be negative")
+	check(c.Webhooks.BatchSize > 0, "webhooks.batchSize must be positive")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.maxAttempts must be positive")
+	check(c.Webhooks.BackoffBase > 0 && c.Webhooks.BackoffBase <= c.Webhooks.BackoffMax, "webhooks.backoffBase must be positive and not above webhooks.backoffMax")
+
+	check(c.Events.History > 0, "events.history must be positive")
+	check(c.Events.Heartbeat > 0, "events.heartbeat must be positive")
+
+	check(c.Quota.MaxBytes >= 0, "quota.maxBytes must not be negative")
+	check(c.Quota.MaxDocuments >= 0, "quota.maxDocuments must not be negative")
+	check(c.Quota.GroupMaxBytes >= 0, "quota.groupMaxBytes must not be negative")
+	check(c.Quota.GroupMaxDocuments >= 0, "quota.groupMaxDocuments must not be negative")
+
+	for _, pattern := range slices.Concat(c.MIMEPolicy.User.Allow, c.MIMEPolicy.User.Deny) {
+		check(validMIMEPattern(pattern), "mimePolicy.user: invalid MIME pattern %q", pattern)
+	}
+	for _, pattern := range slices.Concat(c.MIMEPolicy.Admin.Allow, c.MIMEPolicy.Admin.Deny) {
+		check(validMIMEPattern(pattern), "mimePolicy.admin: invalid MIME pattern %q", pattern)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validMIMEPattern принимает "type/subtype" и "type/*".
+func validMIMEPattern(pattern string) bool {
+	major, minor, ok := strings.Cut(pattern, "/")
+	return ok && major != "" && major != "*" && minor != "" && !strings.ContainsAny(pattern, " ;")
+}
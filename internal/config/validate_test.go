@@ -0,0 +1,23 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRequiresIssuerAndAudience(t *testing.T) {
+	cfg := Default()
+	cfg.OIDC.Enabled = true
+	cfg.OIDC.JWKSFile = "jwks.json"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "oidc.issuer") || !strings.Contains(err.Error(), "oidc.audience") {
+		t.Fatalf("got %v, want errors for oidc.issuer and oidc.audience", err)
+	}
+
+	cfg.OIDC.Issuer = "https://idp.example.com"
+	cfg.OIDC.Audience = "document-server"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid OIDC config rejected: %v", err)
+	}
+}
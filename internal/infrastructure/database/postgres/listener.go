@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"document-server/internal/config"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+)
+
+const (
+	listenPingInterval = 30 * time.Second
+	listenMinBackoff   = time.Second
+	listenMaxBackoff   = 30 * time.Second
+)
+
+// NotificationHandler: уведомления между Disconnected и Connected теряются.
+type NotificationHandler interface {
+	Connected()
+	Disconnected()
+	Notify(payload string)
+}
+
+// Listener держит отдельное соединение вне пула sqlx.
+type Listener struct {
+	cfg     *config.DatabaseConfig
+	channel string
+	handler NotificationHandler
+	logger  *slog.Logger
+}
+
+func NewListener(cfg *config.DatabaseConfig, channel string, handler NotificationHandler, logger *slog.Logger) *Listener {
+	return &Listener{cfg: cfg, channel: channel, handler: handler, logger: logger}
+}
+
+func (l *Listener) Run(ctx context.Context) {
+	backoff := listenMinBackoff
+	for {
+		connected, err := l.listen(ctx)
+		l.handler.Disconnected()
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = listenMinBackoff
+		}
+		l.logger.Warn("notification listener disconnected", slog.String("channel", l.channel),
+			slog.String("error", err.Error()), slog.Duration("retry_in", backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, listenMaxBackoff)
+	}
+}
+
+func (l *Listener) listen(ctx context.Context) (connected bool, err error) {
+	connConfig, err := pgx.ParseConfig(dsn(l.cfg))
+	if err != nil {
+		return false, err
+	}
+	// достаточно прервать чтение, CancelRequest не нужен
+	connConfig.BuildContextWatcherHandler = func(pgConn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.DeadlineContextWatcherHandler{Conn: pgConn.Conn()}
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		return false, err
+	}
+	l.handler.Connected()
+	l.logger.Info("notification listener connected", slog.String("channel", l.channel))
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, listenPingInterval)
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			l.handler.Notify(notification.Payload)
+		case ctx.Err() != nil:
+			return true, ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err):
+			if err := conn.Ping(ctx); err != nil {
+				return true, err
+			}
+		default:
+			return true, err
+		}
+	}
+}
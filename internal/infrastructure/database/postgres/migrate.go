@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"document-server/internal/config"
+	"document-server/migrations"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+type MigrationStatus struct {
+	Current uint
+	Dirty   bool
+	Latest  uint
+	Pending []uint
+}
+
+// Migrator держит pg_advisory_lock, так что реплики могут мигрировать одновременно.
+type Migrator struct {
+	m      *migrate.Migrate
+	source source.Driver
+}
+
+// NewMigrator открывает отдельное соединение: драйвер migrate закрывает *sql.DB в Close.
+func NewMigrator(cfg *config.DatabaseConfig, logger *slog.Logger) (*Migrator, error) {
+	db, err := Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := pgxmigrate.WithInstance(db.DB, &pgxmigrate.Config{})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create migrate driver: %w", err)
+	}
+
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "pgx5", driver)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("failed to create migrator: %w", err)
+	}
+	m.Log = &migrateLogger{logger: logger}
+
+	return &Migrator{m: m, source: src}, nil
+}
+
+func (m *Migrator) Up() error {
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+func (m *Migrator) Down(steps int) error {
+	if err := m.m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+func (m *Migrator) Status() (MigrationStatus, error) {
+	var status MigrationStatus
+
+	current, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return status, err
+	}
+	status.Current, status.Dirty = current, dirty
+
+	version, err := m.source.First()
+	for err == nil {
+		status.Latest = version
+		if version > status.Current {
+			status.Pending = append(status.Pending, version)
+		}
+		version, err = m.source.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return status, err
+	}
+	return status, nil
+}
+
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+type migrateLogger struct {
+	logger *slog.Logger
+}
+
+func (l *migrateLogger) Printf(format string, v ...interface{}) {
+	l.logger.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l *migrateLogger) Verbose() bool {
+	return false
+}
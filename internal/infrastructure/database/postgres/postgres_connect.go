@@ -10,10 +10,7 @@ import (
 )
 
 func Connect(cfg *config.DatabaseConfig) (*sqlx.DB, error) {
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name, cfg.SSLMode)
-
-	db, err := sqlx.Connect("pgx", dsn)
+	db, err := sqlx.Connect("pgx", dsn(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to db with sqlx: %w", err)
 	}
@@ -24,3 +21,8 @@ func Connect(cfg *config.DatabaseConfig) (*sqlx.DB, error) {
 
 	return db, nil
 }
+
+func dsn(cfg *config.DatabaseConfig) string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
+		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name, cfg.SSLMode)
+}
@@ -0,0 +1,163 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type jwksServer struct {
+	mu       sync.Mutex
+	keys     map[string]ed25519.PublicKey
+	status   int
+	requests int
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	var set jsonWebKeySet
+	for kid, key := range s.keys {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			Kid: kid,
+			Use: "sig",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		})
+	}
+	_ = json.NewEncoder(w).Encode(set)
+}
+
+func (s *jwksServer) set(keys map[string]ed25519.PublicKey, status int) {
+	s.mu.Lock()
+	s.keys = keys
+	s.status = status
+	s.mu.Unlock()
+}
+
+func (s *jwksServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func newPublicKey(t *testing.T) ed25519.PublicKey {
+	t.Helper()
+	key, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// backdate сдвигает время последней загрузки, чтобы не ждать в тесте.
+func backdate(ks *KeySet, d time.Duration) {
+	ks.mu.Lock()
+	ks.fetchedAt = ks.fetchedAt.Add(-d)
+	ks.mu.Unlock()
+}
+
+func TestRemoteKeySetRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKey, newKey := newPublicKey(t), newPublicKey(t)
+
+	srv := &jwksServer{keys: map[string]ed25519.PublicKey{"old": oldKey}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ks, err := NewRemoteKeySet(ctx, ts.URL, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := ks.Key(ctx, "old"); err != nil || !oldKey.Equal(key) {
+		t.Fatalf("Key(old) = %v, %v", key, err)
+	}
+
+	srv.set(map[string]ed25519.PublicKey{"new": newKey}, 0)
+	backdate(ks, refreshThrottle)
+
+	key, err := ks.Key(ctx, "new")
+	if err != nil || !newKey.Equal(key) {
+		t.Fatalf("Key(new) after rotation = %v, %v", key, err)
+	}
+	if got := srv.count(); got != 2 {
+		t.Fatalf("requests = %d, want 2", got)
+	}
+
+	// старый ключ пропал из набора и сразу после загрузки повторно не ищется
+	if _, err := ks.Key(ctx, "old"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Key(old) after rotation err = %v, want ErrKeyNotFound", err)
+	}
+	if got := srv.count(); got != 2 {
+		t.Fatalf("requests = %d, want 2", got)
+	}
+}
+
+func TestRemoteKeySetThrottlesUnknownKid(t *testing.T) {
+	ctx := context.Background()
+	key := newPublicKey(t)
+
+	srv := &jwksServer{keys: map[string]ed25519.PublicKey{"current": key}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ks, err := NewRemoteKeySet(ctx, ts.URL, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := ks.Key(ctx, "unknown"); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("Key(unknown) err = %v, want ErrKeyNotFound", err)
+		}
+	}
+	if got := srv.count(); got != 1 {
+		t.Fatalf("requests within throttle = %d, want 1", got)
+	}
+
+	backdate(ks, refreshThrottle)
+	if _, err := ks.Key(ctx, "unknown"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Key(unknown) err = %v, want ErrKeyNotFound", err)
+	}
+	if got := srv.count(); got != 2 {
+		t.Fatalf("requests after throttle = %d, want 2", got)
+	}
+}
+
+func TestRemoteKeySetKeepsKnownKeyWhenRefreshFails(t *testing.T) {
+	ctx := context.Background()
+	key := newPublicKey(t)
+
+	srv := &jwksServer{keys: map[string]ed25519.PublicKey{"current": key}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ks, err := NewRemoteKeySet(ctx, ts.URL, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.set(nil, http.StatusServiceUnavailable)
+	backdate(ks, 2*time.Minute)
+
+	got, err := ks.Key(ctx, "current")
+	if err != nil || !key.Equal(got) {
+		t.Fatalf("Key(current) with failing IdP = %v, %v", got, err)
+	}
+	if n := srv.count(); n != 2 {
+		t.Fatalf("requests = %d, want 2", n)
+	}
+}
@@ -1,20 +1,20 @@
 package logger
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"strings"
 )
 
-func New(levelStr string) *slog.Logger {
-	level := parseLevel(levelStr)
-
+// New создает логгер, уровень которого можно менять на лету через level.
+func New(level *slog.LevelVar) *slog.Logger {
 	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: level,
 	}))
 }
 
-func parseLevel(s string) slog.Level {
+func ParseLevel(s string) slog.Level {
 	switch strings.ToLower(s) {
 	case "debug":
 		return slog.LevelDebug
@@ -26,3 +26,18 @@ func parseLevel(s string) slog.Level {
 		return slog.LevelInfo
 	}
 }
+
+type ctxKey struct{}
+
+// WithContext привязывает логгер запроса к контексту.
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext возвращает логгер запроса или fallback, если его нет.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return fallback
+}
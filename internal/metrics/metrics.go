@@ -0,0 +1,273 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "docsrv"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	transferredBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "document_bytes_total",
+		Help:      "Document content bytes by direction (upload or download).",
+	}, []string{"direction"})
+
+	storageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Storage operation latency by store, operation and result.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"store", "operation", "result"})
+
+	janitorRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_janitor_runs_total",
+		Help:      "Token janitor runs by result.",
+	}, []string{"result"})
+
+	janitorDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_janitor_deleted_tokens_total",
+		Help:      "Expired session tokens removed by the token janitor.",
+	})
+
+	janitorLastRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "token_janitor_last_run_timestamp_seconds",
+		Help:      "Unix time of the last token janitor run.",
+	})
+
+	integrityFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "integrity_failures_total",
+		Help:      "Stored files whose SHA-256 did not match on read or scrub.",
+	})
+
+	reconcilerRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconciler_runs_total",
+		Help:      "Storage reconciler runs by result.",
+	}, []string{"result"})
+
+	reconcilerOrphans = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "reconciler_orphaned_files",
+		Help:      "Files without a document row found by the last reconciler run.",
+	})
+
+	reconcilerMissing = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "reconciler_missing_content",
+		Help:      "Document rows whose file was missing in the last reconciler run.",
+	})
+
+	reconcilerLastRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "reconciler_last_run_timestamp_seconds",
+		Help:      "Unix time of the last reconciler run.",
+	})
+
+	retentionRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retention_runs_total",
+		Help:      "Expired document and trash sweeps by result.",
+	}, []string{"result"})
+
+	retentionDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retention_deleted_documents_total",
+		Help:      "Documents deleted because their expires_at passed.",
+	})
+
+	retentionPurged = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retention_purged_documents_total",
+		Help:      "Documents purged from the trash after retention.trashDays.",
+	})
+
+	retentionLastRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "retention_last_run_timestamp_seconds",
+		Help:      "Unix time of the last expired document sweep.",
+	})
+
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by result: delivered, retry or dead.",
+	}, []string{"result"})
+
+	feedSubscribers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "event_feed_subscribers",
+		Help:      "Clients connected to the document event feed.",
+	})
+
+	cacheInvalidationConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_invalidation_connected",
+		Help:      "1 while the cache invalidation listener is connected to the database.",
+	})
+
+	webhookRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_runs_total",
+		Help:      "Webhook dispatcher passes by result.",
+	}, []string{"result"})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func ObserveHTTPRequest(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+func AddUploadedBytes(n int) {
+	transferredBytes.WithLabelValues("upload").Add(float64(n))
+}
+
+func AddDownloadedBytes(n int) {
+	transferredBytes.WithLabelValues("download").Add(float64(n))
+}
+
+// ObserveStorageOperation используется как defer metrics.ObserveStorageOperation("document", "create", time.Now(), &err).
+func ObserveStorageOperation(store, operation string, start time.Time, err *error) {
+	result := "ok"
+	if err != nil && *err != nil {
+		result = "error"
+	}
+	storageDuration.WithLabelValues(store, operation, result).Observe(time.Since(start).Seconds())
+}
+
+func ObserveTokenJanitorRun(deleted int64, err error) {
+	janitorLastRun.SetToCurrentTime()
+	if err != nil {
+		janitorRuns.WithLabelValues("error").Inc()
+		return
+	}
+	janitorRuns.WithLabelValues("ok").Inc()
+	janitorDeleted.Add(float64(deleted))
+}
+
+func ObserveRetentionRun(deleted, purged int, err error) {
+	retentionLastRun.SetToCurrentTime()
+	retentionDeleted.Add(float64(deleted))
+	retentionPurged.Add(float64(purged))
+	if err != nil {
+		retentionRuns.WithLabelValues("error").Inc()
+		return
+	}
+	retentionRuns.WithLabelValues("ok").Inc()
+}
+
+func ObserveWebhookDelivery(result string) {
+	webhookDeliveries.WithLabelValues(result).Inc()
+}
+
+func ObserveWebhookRun(err error) {
+	if err != nil {
+		webhookRuns.WithLabelValues("error").Inc()
+		return
+	}
+	webhookRuns.WithLabelValues("ok").Inc()
+}
+
+func SetFeedSubscribers(n int) {
+	feedSubscribers.Set(float64(n))
+}
+
+func SetCacheInvalidationConnected(connected bool) {
+	if connected {
+		cacheInvalidationConnected.Set(1)
+		return
+	}
+	cacheInvalidationConnected.Set(0)
+}
+
+func IncIntegrityFailures() {
+	integrityFailures.Inc()
+}
+
+func ObserveReconcilerRun(orphans, missing int, err error) {
+	reconcilerLastRun.SetToCurrentTime()
+	if err != nil {
+		reconcilerRuns.WithLabelValues("error").Inc()
+		return
+	}
+	reconcilerRuns.WithLabelValues("ok").Inc()
+	reconcilerOrphans.Set(float64(orphans))
+	reconcilerMissing.Set(float64(missing))
+}
+
+func RegisterDBStats(db *sqlx.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db.DB, name))
+}
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int64
+	Errors    uint64
+}
+
+func RegisterCache(name string, stats func() CacheStats) {
+	prometheus.MustRegister(&cacheCollector{name: name, stats: stats})
+}
+
+var (
+	cacheHitsDesc      = prometheus.NewDesc(namespace+"_cache_hits_total", "Cache hits.", []string{"cache"}, nil)
+	cacheMissesDesc    = prometheus.NewDesc(namespace+"_cache_misses_total", "Cache misses.", []string{"cache"}, nil)
+	cacheEvictionsDesc = prometheus.NewDesc(namespace+"_cache_evictions_total", "Entries evicted because of expiry or capacity.", []string{"cache"}, nil)
+	cacheSizeDesc      = prometheus.NewDesc(namespace+"_cache_entries", "Current number of cache entries.", []string{"cache"}, nil)
+	cacheErrorsDesc    = prometheus.NewDesc(namespace+"_cache_errors_total", "Failed requests to a shared cache server.", []string{"cache"}, nil)
+)
+
+// cacheCollector читает счетчики кэша в момент скрейпа, чтобы кэш не зависел от prometheus.
+type cacheCollector struct {
+	name  string
+	stats func() CacheStats
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheSizeDesc
+	ch <- cacheErrorsDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(s.Hits), c.name)
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(s.Misses), c.name)
+	ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(s.Evictions), c.name)
+	ch <- prometheus.MustNewConstMetric(cacheSizeDesc, prometheus.GaugeValue, float64(s.Size), c.name)
+	ch <- prometheus.MustNewConstMetric(cacheErrorsDesc, prometheus.CounterValue, float64(s.Errors), c.name)
+}
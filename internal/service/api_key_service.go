@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"document-server/internal/api/models"
+	"document-server/internal/logger"
+	"document-server/internal/storage"
+	apiKeyStorage "document-server/internal/storage/apikey"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+type APIKeyService struct {
+	apiKeyStorage APIKeyStorage
+	authenticator Authenticator
+	logger        *slog.Logger
+}
+
+func NewAPIKeyService(apiKeyStorage APIKeyStorage, authenticator Authenticator, logger *slog.Logger) *APIKeyService {
+	return &APIKeyService{
+		apiKeyStorage: apiKeyStorage,
+		authenticator: authenticator,
+		logger:        logger,
+	}
+}
+
+func (s *APIKeyService) log(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req models.APIKeyCreateRequestDTO) (*models.APIKeyCreateResponseDTO, error) {
+	principal, err := s.sessionPrincipal(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, semerr.NewBadRequestError(errors.New("api key name is required"))
+	}
+	if len(req.Scopes) == 0 {
+		return nil, semerr.NewBadRequestError(errors.New("at least one scope is required"))
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(knownScopes, scope) {
+			return nil, semerr.NewBadRequestError(errors.New("unknown scope " + scope))
+		}
+	}
+	for _, entry := range req.AllowedIPs {
+		if _, err := netip.ParsePrefix(entry); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(entry); err != nil {
+			return nil, semerr.NewBadRequestError(errors.New("invalid allowed ip " + entry))
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, semerr.NewBadRequestError(errors.New("expires_at must be in the future"))
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		s.log(ctx).Error("failed to generate api key", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	plain := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key := apiKeyStorage.APIKey{
+		ID:         uuid.New(),
+		UserID:     principal.User.ID,
+		Name:       req.Name,
+		KeyPrefix:  apiKeyDisplayPrefix(plain),
+		KeyHash:    hashAPIKey(plain),
+		Scopes:     req.Scopes,
+		AllowedIPs: req.AllowedIPs,
+		CreatedAt:  time.Now(),
+	}
+	if req.ExpiresAt != nil {
+		key.ExpiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	if err := s.apiKeyStorage.Create(ctx, key); err != nil {
+		s.log(ctx).Error("failed to create api key", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("api key created", slog.String("key_id", key.ID.String()), slog.String("user", principal.User.Login))
+	return &models.APIKeyCreateResponseDTO{
+		APIKeyDTO: apiKeyToDTO(key),
+		Key:       plain,
+	}, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, token string) ([]models.APIKeyDTO, error) {
+	principal, err := s.sessionPrincipal(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := s.apiKeyStorage.ListByUserID(ctx, principal.User.ID)
+	if err != nil {
+		s.log(ctx).Error("failed to list api keys", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	result := make([]models.APIKeyDTO, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, apiKeyToDTO(key))
+	}
+	return result, nil
+}
+
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, token, id string) error {
+	principal, err := s.sessionPrincipal(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	keyID, err := uuid.Parse(id)
+	if err != nil {
+		return semerr.NewBadRequestError(errors.New("invalid api key ID"))
+	}
+
+	if err := s.apiKeyStorage.Revoke(ctx, keyID, principal.User.ID); err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return semerr.NewNotFoundError(err)
+		}
+		s.log(ctx).Error("failed to revoke api key", slog.String("key_id", id), slog.String("error", err.Error()))
+		return semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("api key revoked", slog.String("key_id", id), slog.String("user", principal.User.Login))
+	return nil
+}
+
+// sessionPrincipal не дает управлять ключами с помощью самих ключей.
+func (s *APIKeyService) sessionPrincipal(ctx context.Context, token string) (*Principal, error) {
+	principal, err := s.authenticator.Authenticate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if principal.IsAPIKey() {
+		return nil, semerr.NewForbiddenError(errors.New("api keys cannot manage api keys"))
+	}
+	return principal, nil
+}
+
+func apiKeyToDTO(key apiKeyStorage.APIKey) models.APIKeyDTO {
+	dto := models.APIKeyDTO{
+		ID:         key.ID.String(),
+		Name:       key.Name,
+		Prefix:     key.KeyPrefix,
+		Scopes:     key.Scopes,
+		AllowedIPs: key.AllowedIPs,
+		CreatedAt:  key.CreatedAt,
+	}
+	if key.ExpiresAt.Valid {
+		dto.ExpiresAt = &key.ExpiresAt.Time
+	}
+	if key.LastUsedAt.Valid {
+		dto.LastUsedAt = &key.LastUsedAt.Time
+	}
+	if key.RevokedAt.Valid {
+		dto.RevokedAt = &key.RevokedAt.Time
+	}
+	return dto
+}
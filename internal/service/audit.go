@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/api/models"
+	"document-server/internal/logger"
+	auditStorage "document-server/internal/storage/audit"
+	"document-server/internal/tracing"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+const (
+	AuditUpload   = "document.upload"
+	AuditRead     = "document.read"
+	AuditDownload = "document.download"
+	AuditDelete   = "document.delete"
+	AuditRestore  = "document.restore"
+	AuditPurge    = "document.purge"
+	AuditGrant    = "document.grant"
+	AuditLogin    = "auth.login"
+	AuditLogout   = "auth.logout"
+	AuditRegister = "user.register"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeDenied  = "denied"
+	OutcomeFailure = "failure"
+)
+
+// Системные участники для действий, которые выполняются не по запросу пользователя.
+const (
+	actorAdminToken = "admin-token"
+	actorDocsctl    = "docsctl"
+	actorRetention  = "retention"
+)
+
+// AuditLog не отменяет действие при ошибке записи, а только пишет ее в лог.
+type AuditLog struct {
+	storage AuditStorage
+	logger  *slog.Logger
+}
+
+func NewAuditLog(storage AuditStorage, logger *slog.Logger) *AuditLog {
+	return &AuditLog{storage: storage, logger: logger}
+}
+
+// Record: Forbidden и Unauthorized - denied, прочие ошибки - failure.
+func (a *AuditLog) Record(ctx context.Context, action, actor, target string, err error) {
+	a.RecordDetail(ctx, action, actor, target, "", err)
+}
+
+func (a *AuditLog) RecordDetail(ctx context.Context, action, actor, target, detail string, err error) {
+	meta := requestMetaFromContext(ctx)
+	event := auditStorage.Event{
+		Action:    action,
+		Outcome:   auditOutcome(err),
+		Actor:     nullString(actor),
+		Target:    nullString(target),
+		IP:        nullString(clientIPFromContext(ctx)),
+		UserAgent: nullString(meta.UserAgent),
+		RequestID: nullString(meta.RequestID),
+		Detail:    nullString(detail),
+	}
+	if err != nil && detail != "" {
+		event.Detail = nullString(detail + ": " + err.Error())
+	} else if err != nil {
+		event.Detail = nullString(err.Error())
+	}
+
+	// Запрос мог уже завершиться (клиент отключился), а событие все равно нужно записать.
+	if err := a.storage.Append(context.WithoutCancel(ctx), event); err != nil {
+		logger.FromContext(ctx, a.logger).Error("failed to write audit event", slog.String("action", action), slog.String("error", err.Error()))
+	}
+}
+
+func auditOutcome(err error) string {
+	var unauthorized semerr.UnauthorizedError
+	var forbidden semerr.ForbiddenError
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.As(err, &unauthorized), errors.As(err, &forbidden):
+		return OutcomeDenied
+	default:
+		return OutcomeFailure
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// AuditService отдает журнал аудита администраторам.
+type AuditService struct {
+	storage     AuditStorage
+	userService *UserService
+	logger      *slog.Logger
+}
+
+func NewAuditService(storage AuditStorage, userService *UserService, logger *slog.Logger) *AuditService {
+	return &AuditService{storage: storage, userService: userService, logger: logger}
+}
+
+func (s *AuditService) log(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+// Query возвращает события по фильтру, начиная с последних; по умолчанию 100.
+func (s *AuditService) Query(ctx context.Context, query models.AuditQueryDTO) (_ []models.AuditEventDTO, err error) {
+	ctx, span := tracing.Start(ctx, "AuditService.Query")
+	defer tracing.End(span, &err)
+
+	filter, err := auditFilter(query)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+
+	events, err := s.storage.List(ctx, filter)
+	if err != nil {
+		s.log(ctx).Error("failed to query audit log", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	result := make([]models.AuditEventDTO, 0, len(events))
+	for _, event := range events {
+		result = append(result, auditEventDTO(event))
+	}
+	return result, nil
+}
+
+// Export передает в fn все события по фильтру в порядке записи; limit игнорируется.
+func (s *AuditService) Export(ctx context.Context, query models.AuditQueryDTO, fn func(models.AuditEventDTO) error) (err error) {
+	ctx, span := tracing.Start(ctx, "AuditService.Export")
+	defer tracing.End(span, &err)
+
+	filter, err := auditFilter(query)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.Export(ctx, filter, func(event auditStorage.Event) error {
+		return fn(auditEventDTO(event))
+	})
+	if err != nil {
+		s.log(ctx).Error("audit export failed", slog.String("error", err.Error()))
+		return semerr.NewInternalServerError(err)
+	}
+	return nil
+}
+
+func (s *AuditService) QueryAsAdmin(ctx context.Context, adminToken string, query models.AuditQueryDTO) ([]models.AuditEventDTO, error) {
+	if !s.userService.IsAdmin(ctx, adminToken) {
+		return nil, semerr.NewUnauthorizedError(errors.New("invalid admin token"))
+	}
+	return s.Query(ctx, query)
+}
+
+func (s *AuditService) ExportAsAdmin(ctx context.Context, adminToken string, query models.AuditQueryDTO, fn func(models.AuditEventDTO) error) error {
+	if !s.userService.IsAdmin(ctx, adminToken) {
+		return semerr.NewUnauthorizedError(errors.New("invalid admin token"))
+	}
+	return s.Export(ctx, query, fn)
+}
+
+func auditFilter(query models.AuditQueryDTO) (auditStorage.Filter, error) {
+	filter := auditStorage.Filter{Actor: query.Actor, Target: query.Document, Action: query.Action}
+
+	var err error
+	if query.From != "" {
+		if filter.From, err = time.Parse(time.RFC3339, query.From); err != nil {
+			return filter, semerr.NewBadRequestError(errors.New("from must be an RFC 3339 time"))
+		}
+	}
+	if query.To != "" {
+		if filter.To, err = time.Parse(time.RFC3339, query.To); err != nil {
+			return filter, semerr.NewBadRequestError(errors.New("to must be an RFC 3339 time"))
+		}
+	}
+	if query.Limit != "" {
+		if filter.Limit, err = strconv.Atoi(query.Limit); err != nil || filter.Limit <= 0 {
+			return filter, semerr.NewBadRequestError(errors.New("limit must be a positive number"))
+		}
+	}
+	return filter, nil
+}
+
+func auditEventDTO(event auditStorage.Event) models.AuditEventDTO {
+	return models.AuditEventDTO{
+		ID:        event.ID,
+		Time:      event.OccurredAt,
+		Action:    event.Action,
+		Outcome:   event.Outcome,
+		Actor:     event.Actor.String,
+		Target:    event.Target.String,
+		IP:        event.IP.String,
+		UserAgent: event.UserAgent.String,
+		RequestID: event.RequestID.String,
+		Detail:    event.Detail.String,
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"document-server/internal/config"
+	"document-server/internal/logger"
+	"document-server/internal/storage"
+	userStorage "document-server/internal/storage/user"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+const (
+	ScopeDocsRead   = "docs:read"
+	ScopeDocsWrite  = "docs:write"
+	ScopeDocsDelete = "docs:delete"
+)
+
+var knownScopes = []string{ScopeDocsRead, ScopeDocsWrite, ScopeDocsDelete}
+
+const apiKeyPrefix = "dsk_"
+
+// Principal - это аутентифицированный пользователь вместе с ограничениями его учетных данных.
+type Principal struct {
+	User     userStorage.User
+	APIKeyID uuid.UUID
+	Scopes   []string
+}
+
+// IsAPIKey сообщает, что запрос выполнен с API ключом, а не с сессионным токеном.
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKeyID != uuid.Nil
+}
+
+// Can проверяет scope; сессионные токены не ограничены.
+func (p *Principal) Can(scope string) bool {
+	if !p.IsAPIKey() {
+		return true
+	}
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Authenticator превращает переданный клиентом токен в Principal.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// CompositeAuthenticator отправляет JWT во внешний аутентификатор, остальные токены - в локальный.
+type CompositeAuthenticator struct {
+	local    Authenticator
+	external Authenticator
+}
+
+func NewCompositeAuthenticator(local, external Authenticator) *CompositeAuthenticator {
+	return &CompositeAuthenticator{local: local, external: external}
+}
+
+func (a *CompositeAuthenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if a.external != nil && looksLikeJWT(token) {
+		return a.external.Authenticate(ctx, token)
+	}
+	return a.local.Authenticate(ctx, token)
+}
+
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+type TokenAuthenticator struct {
+	userStorage   UserStorage
+	tokenStorage  TokenStorage
+	apiKeyStorage APIKeyStorage
+	logger        *slog.Logger
+	sessionExpiry bool
+}
+
+func NewTokenAuthenticator(userStorage UserStorage, tokenStorage TokenStorage, apiKeyStorage APIKeyStorage, auth config.AuthConfig, logger *slog.Logger) *TokenAuthenticator {
+	return &TokenAuthenticator{
+		userStorage:   userStorage,
+		tokenStorage:  tokenStorage,
+		apiKeyStorage: apiKeyStorage,
+		logger:        logger,
+		sessionExpiry: auth.SessionExpiry,
+	}
+}
+
+func (a *TokenAuthenticator) log(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, a.logger)
+}
+
+func (a *TokenAuthenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if token == "" {
+		return nil, semerr.NewUnauthorizedError(errors.New("token required"))
+	}
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		return a.authenticateAPIKey(ctx, token)
+	}
+	return a.authenticateSession(ctx, token)
+}
+
+func (a *TokenAuthenticator) authenticateSession(ctx context.Context, token string) (*Principal, error) {
+	userToken, err := a.tokenStorage.GetByToken(ctx, token)
+	if err != nil {
+		if !errors.Is(err, storage.ErrTokenNotFound) {
+			a.log(ctx).Error("failed to query token", slog.String("error", err.Error()))
+			return nil, semerr.NewInternalServerError(err)
+		}
+		a.log(ctx).Error("token not found", slog.String("token", token))
+		return nil, semerr.NewBadRequestError(errors.New("token not found"))
+	}
+
+	if a.sessionExpiry && time.Now().After(userToken.ExpiresAt) {
+		a.log(ctx).Error("token expired", slog.String("user_id", userToken.UserID.String()))
+		return nil, semerr.NewUnauthorizedError(errors.New("token expired"))
+	}
+
+	user, err := a.userStorage.GetUserByID(ctx, userToken.UserID)
+	if err != nil {
+		a.log(ctx).Error("user not found", slog.String("user_id", userToken.UserID.String()))
+		return nil, semerr.NewBadRequestError(errors.New("user not found"))
+	}
+
+	return &Principal{User: user}, nil
+}
+
+func (a *TokenAuthenticator) authenticateAPIKey(ctx context.Context, token string) (*Principal, error) {
+	key, err := a.apiKeyStorage.GetByHash(ctx, hashAPIKey(token))
+	if err != nil {
+		if !errors.Is(err, storage.ErrAPIKeyNotFound) {
+			a.log(ctx).Error("failed to query api key", slog.String("error", err.Error()))
+			return nil, semerr.NewInternalServerError(err)
+		}
+		a.log(ctx).Error("api key not found", slog.String("prefix", apiKeyDisplayPrefix(token)))
+		return nil, semerr.NewBadRequestError(errors.New("token not found"))
+	}
+
+	now := time.Now()
+	if key.RevokedAt.Valid {
+		a.log(ctx).Error("api key revoked", slog.String("key_id", key.ID.String()))
+		return nil, semerr.NewUnauthorizedError(errors.New("api key revoked"))
+	}
+	if key.ExpiresAt.Valid && now.After(key.ExpiresAt.Time) {
+		a.log(ctx).Error("api key expired", slog.String("key_id", key.ID.String()))
+		return nil, semerr.NewUnauthorizedError(errors.New("api key expired"))
+	}
+
+	if len(key.AllowedIPs) > 0 {
+		ip := clientIPFromContext(ctx)
+		if !ipAllowed(ip, key.AllowedIPs) {
+			a.log(ctx).Error("api key used from disallowed address", slog.String("key_id", key.ID.String()), slog.String("ip", ip))
+			return nil, semerr.NewForbiddenError(errors.New("api key is not allowed from this address"))
+		}
+	}
+
+	user, err := a.userStorage.GetUserByID(ctx, key.UserID)
+	if err != nil {
+		a.log(ctx).Error("user not found", slog.String("user_id", key.UserID.String()))
+		return nil, semerr.NewBadRequestError(errors.New("user not found"))
+	}
+
+	if err := a.apiKeyStorage.TouchLastUsed(ctx, key.ID, now); err != nil {
+		a.log(ctx).Warn("failed to update api key last use", slog.String("key_id", key.ID.String()), slog.String("error", err.Error()))
+	}
+
+	return &Principal{User: user, APIKeyID: key.ID, Scopes: key.Scopes}, nil
+}
+
+// requireScope аутентифицирует токен и проверяет, что он допускает операцию.
+func requireScope(ctx context.Context, a Authenticator, token, scope string) (*Principal, error) {
+	principal, err := a.Authenticate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.Can(scope) {
+		return nil, semerr.NewForbiddenError(errors.New("api key lacks scope " + scope))
+	}
+	return principal, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func apiKeyDisplayPrefix(key string) string {
+	const n = len(apiKeyPrefix) + 8
+	if len(key) < n {
+		return key
+	}
+	return key[:n]
+}
+
+func ipAllowed(ip string, allowed []string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, entry := range allowed {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			if prefix.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if allowedAddr, err := netip.ParseAddr(entry); err == nil && allowedAddr.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/config"
+	"document-server/internal/storage"
+	apiKeyStorage "document-server/internal/storage/apikey"
+	tokenStorage "document-server/internal/storage/token"
+	userStorage "document-server/internal/storage/user"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// memoryAPIKeys - API ключи в памяти, найденные по хэшу.
+type memoryAPIKeys struct {
+	APIKeyStorage
+
+	keys map[string]apiKeyStorage.APIKey
+}
+
+func (s *memoryAPIKeys) GetByHash(_ context.Context, hash string) (apiKeyStorage.APIKey, error) {
+	key, ok := s.keys[hash]
+	if !ok {
+		return apiKeyStorage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (s *memoryAPIKeys) TouchLastUsed(context.Context, uuid.UUID, time.Time) error {
+	return nil
+}
+
+type memoryTokens struct {
+	TokenStorage
+
+	tokens map[string]tokenStorage.UserToken
+}
+
+func (s *memoryTokens) GetByToken(_ context.Context, token string) (tokenStorage.UserToken, error) {
+	t, ok := s.tokens[token]
+	if !ok {
+		return tokenStorage.UserToken{}, storage.ErrTokenNotFound
+	}
+	return t, nil
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	user := userStorage.User{ID: uuid.New(), Login: "ci-bot"}
+	past := sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+	future := sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+
+	tests := []struct {
+		name    string
+		key     apiKeyStorage.APIKey
+		ip      string
+		scope   string
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			key:   apiKeyStorage.APIKey{Scopes: []string{ScopeDocsWrite}, ExpiresAt: future},
+			scope: ScopeDocsWrite,
+		},
+		{
+			name:    "missing scope",
+			key:     apiKeyStorage.APIKey{Scopes: []string{ScopeDocsRead}},
+			scope:   ScopeDocsDelete,
+			wantErr: semerr.ForbiddenError{},
+		},
+		{
+			name:  "allowed network",
+			key:   apiKeyStorage.APIKey{Scopes: []string{ScopeDocsRead}, AllowedIPs: []string{"10.0.0.0/8"}},
+			ip:    "10.1.2.3",
+			scope: ScopeDocsRead,
+		},
+		{
+			name:    "address outside allowlist",
+			key:     apiKeyStorage.APIKey{Scopes: []string{ScopeDocsRead}, AllowedIPs: []string{"10.0.0.0/8", "192.0.2.7"}},
+			ip:      "192.0.2.8",
+			scope:   ScopeDocsRead,
+			wantErr: semerr.ForbiddenError{},
+		},
+		{
+			name:    "unknown address",
+			key:     apiKeyStorage.APIKey{Scopes: []string{ScopeDocsRead}, AllowedIPs: []string{"10.0.0.0/8"}},
+			scope:   ScopeDocsRead,
+			wantErr: semerr.ForbiddenError{},
+		},
+		{
+			name:    "revoked",
+			key:     apiKeyStorage.APIKey{Scopes: []string{ScopeDocsRead}, RevokedAt: past},
+			scope:   ScopeDocsRead,
+			wantErr: semerr.UnauthorizedError{},
+		},
+		{
+			name:    "expired",
+			key:     apiKeyStorage.APIKey{Scopes: []string{ScopeDocsRead}, ExpiresAt: past},
+			scope:   ScopeDocsRead,
+			wantErr: semerr.UnauthorizedError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := apiKeyPrefix + "0123456789abcdef"
+			key := tt.key
+			key.ID, key.UserID = uuid.New(), user.ID
+			a := NewTokenAuthenticator(
+				&memoryUserStorage{users: []userStorage.User{user}},
+				&memoryTokens{},
+				&memoryAPIKeys{keys: map[string]apiKeyStorage.APIKey{hashAPIKey(token): key}},
+				config.AuthConfig{}, discardLogger(),
+			)
+
+			ctx := context.Background()
+			if tt.ip != "" {
+				ctx = WithClientIP(ctx, tt.ip)
+			}
+			principal, err := requireScope(ctx, a, token, tt.scope)
+			if tt.wantErr != nil {
+				if !sameErrorType(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %T", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if principal.User.ID != user.ID || principal.APIKeyID != key.ID {
+				t.Errorf("principal %+v, want user %s with key %s", principal, user.ID, key.ID)
+			}
+		})
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	user := userStorage.User{ID: uuid.New(), Login: "alice"}
+	tokens := &memoryTokens{tokens: map[string]tokenStorage.UserToken{
+		"expired": {Token: "expired", UserID: user.ID, ExpiresAt: time.Now().Add(-time.Hour)},
+	}}
+
+	for _, enforce := range []bool{false, true} {
+		a := NewTokenAuthenticator(&memoryUserStorage{users: []userStorage.User{user}}, tokens, &memoryAPIKeys{},
+			config.AuthConfig{SessionExpiry: enforce}, discardLogger())
+		_, err := a.Authenticate(context.Background(), "expired")
+		if enforce && !sameErrorType(err, semerr.UnauthorizedError{}) {
+			t.Errorf("sessionExpiry on: got error %v, want unauthorized", err)
+		}
+		if !enforce && err != nil {
+			t.Errorf("sessionExpiry off: got error %v, want the token accepted", err)
+		}
+	}
+}
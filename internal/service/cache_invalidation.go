@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"document-server/internal/cache"
+	"document-server/internal/metrics"
+	"log/slog"
+	"time"
+)
+
+// CacheInvalidation очищает кэш целиком после переподключения: пропущенные уведомления не узнать.
+type CacheInvalidation struct {
+	cache       cache.Local
+	fallbackTTL time.Duration
+	logger      *slog.Logger
+}
+
+func NewCacheInvalidation(cache cache.Local, fallbackTTL time.Duration, logger *slog.Logger) *CacheInvalidation {
+	c := &CacheInvalidation{cache: cache, fallbackTTL: fallbackTTL, logger: logger}
+	c.Disconnected()
+	return c
+}
+
+func (c *CacheInvalidation) Connected() {
+	c.cache.Clear()
+	c.cache.LimitTTL(0)
+	metrics.SetCacheInvalidationConnected(true)
+}
+
+func (c *CacheInvalidation) Disconnected() {
+	c.cache.LimitTTL(c.fallbackTTL)
+	metrics.SetCacheInvalidationConnected(false)
+}
+
+// Notify получает ID измененного документа.
+func (c *CacheInvalidation) Notify(payload string) {
+	c.cache.Delete(context.Background(), "document:"+payload)
+	c.logger.Debug("cache entry invalidated", slog.String("doc_id", payload))
+}
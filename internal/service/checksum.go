@@ -0,0 +1,61 @@
+package service
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"document-server/internal/api/models"
+	blobStorage "document-server/internal/storage/blob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// checkExpectedChecksums сравнивает суммы, переданные клиентом, с посчитанными при записи.
+func checkExpectedChecksums(meta models.DocumentUploadMetaDTO, staged *blobStorage.StagedFile) error {
+	if expected := strings.ToLower(meta.SHA256); expected != "" && expected != staged.SHA256() {
+		return semerr.NewBadRequestError(fmt.Errorf("sha256 mismatch: expected %s, got %s", expected, staged.SHA256()))
+	}
+	if expected := strings.ToLower(meta.MD5); expected != "" {
+		if staged.MD5() == "" {
+			return semerr.NewBadRequestError(errors.New("md5 checksums are disabled on this server"))
+		}
+		if expected != staged.MD5() {
+			return semerr.NewBadRequestError(fmt.Errorf("md5 mismatch: expected %s, got %s", expected, staged.MD5()))
+		}
+	}
+	return nil
+}
+
+// hashReader считает SHA-256 и, если withMD5, MD5 потока.
+func hashReader(r io.Reader, withMD5 bool) (sha string, md string, err error) {
+	shaHash := sha256.New()
+	writers := []io.Writer{shaHash}
+	var mdHash hash.Hash
+	if withMD5 {
+		mdHash = md5.New()
+		writers = append(writers, mdHash)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), r); err != nil {
+		return "", "", err
+	}
+	if mdHash != nil {
+		md = hex.EncodeToString(mdHash.Sum(nil))
+	}
+	return hex.EncodeToString(shaHash.Sum(nil)), md, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
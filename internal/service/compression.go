@@ -0,0 +1,24 @@
+package service
+
+import (
+	"document-server/internal/config"
+)
+
+// CompressionPolicy решает, сжимать ли файл при записи.
+type CompressionPolicy struct {
+	enabled   bool
+	minSize   int
+	mimeTypes []string
+}
+
+func NewCompressionPolicy(cfg config.CompressionConfig) *CompressionPolicy {
+	return &CompressionPolicy{enabled: cfg.Enabled, minSize: cfg.MinSize, mimeTypes: cfg.MIMETypes}
+}
+
+// Compress: файл короче head известен целиком и сравнивается с minSize.
+func (p *CompressionPolicy) Compress(mimeType string, head []byte) bool {
+	if p == nil || !p.enabled || len(head) < p.minSize {
+		return false
+	}
+	return matchMIME(p.mimeTypes, mimeType)
+}
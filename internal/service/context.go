@@ -0,0 +1,32 @@
+package service
+
+import "context"
+
+type clientIPKey struct{}
+
+// WithClientIP сохраняет адрес клиента в контексте для проверки allowlist'ов API ключей.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+type requestMetaKey struct{}
+
+// RequestMeta - сведения о HTTP запросе, которые попадают в журнал аудита.
+type RequestMeta struct {
+	UserAgent string
+	RequestID string
+}
+
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+func requestMetaFromContext(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/api/models"
+	"document-server/internal/metrics"
+	"document-server/internal/storage"
+	blobStorage "document-server/internal/storage/blob"
+	documentStorage "document-server/internal/storage/document"
+	"document-server/internal/tracing"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// Методы ниже вызывает docsctl, поэтому токен и права не проверяются.
+
+func (s *DocumentService) AdminListDocuments(ctx context.Context, login string, limit int) (_ []documentStorage.Document, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.AdminListDocuments")
+	defer tracing.End(span, &err)
+
+	docs, err := s.documentStorage.List(ctx, login, limit)
+	if err != nil {
+		s.log(ctx).Error("failed to list documents", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	return docs, nil
+}
+
+// AdminGetDocument возвращает метаданные документа и, для файлов, их содержимое.
+func (s *DocumentService) AdminGetDocument(ctx context.Context, id string) (_ *documentStorage.Document, _ []byte, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.AdminGetDocument")
+	defer tracing.End(span, &err)
+
+	doc, err := s.adminLoadDocument(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !doc.IsFile || !doc.FilePath.Valid {
+		return doc, nil, nil
+	}
+
+	data, err := s.blobs.ReadAll(ctx, blobRef(doc))
+	if err != nil {
+		s.log(ctx).Error("failed to read file", slog.String("path", doc.FilePath.String), slog.String("error", err.Error()))
+		return nil, nil, semerr.NewInternalServerError(err)
+	}
+	return doc, data, nil
+}
+
+func (s *DocumentService) AdminDeleteDocument(ctx context.Context, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.AdminDeleteDocument")
+	defer tracing.End(span, &err)
+
+	defer func() { s.audit.Record(ctx, AuditPurge, actorDocsctl, id, err) }()
+
+	doc, err := s.adminLoadDocument(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.removeDocument(ctx, doc)
+}
+
+func (s *DocumentService) SetLegalHold(ctx context.Context, id string, hold bool) (_ *documentStorage.Document, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.SetLegalHold")
+	defer tracing.End(span, &err)
+
+	doc, err := s.adminLoadDocument(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.documentStorage.SetLegalHold(ctx, doc.ID, hold); err != nil {
+		if errors.Is(err, storage.ErrDocumentNotFound) {
+			return nil, semerr.NewNotFoundError(err)
+		}
+		s.log(ctx).Error("failed to set legal hold", slog.String("doc_id", id), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	s.cache.Delete(ctx, "document:"+doc.ID.String())
+
+	doc.LegalHold = hold
+	s.events.Publish(documentStorage.EventUpdated, doc, doc.GrantedTo)
+	s.log(ctx).Info("legal hold changed", slog.String("doc_id", id), slog.Bool("hold", hold))
+	return doc, nil
+}
+
+// DeleteExpired удаляет документы с истекшим сроком хранения пачками по batchSize.
+func (s *DocumentService) DeleteExpired(ctx context.Context, batchSize int) (deleted int, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.DeleteExpired")
+	defer tracing.End(span, &err)
+
+	for {
+		docs, err := s.documentStorage.ListExpired(ctx, time.Now(), batchSize)
+		if err != nil {
+			return deleted, err
+		}
+		for i := range docs {
+			err := s.removeDocument(ctx, &docs[i])
+			// Удержание могли поставить после выборки - такой документ просто остается.
+			var conflict semerr.ConflictError
+			if errors.As(err, &conflict) {
+				continue
+			}
+			s.audit.RecordDetail(ctx, AuditPurge, actorRetention, docs[i].ID.String(), "expired", err)
+			if err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if len(docs) < batchSize {
+			return deleted, nil
+		}
+	}
+}
+
+func (s *DocumentService) adminLoadDocument(ctx context.Context, id string) (*documentStorage.Document, error) {
+	docUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, semerr.NewBadRequestError(errors.New("invalid document ID"))
+	}
+
+	doc, err := s.documentStorage.GetByID(ctx, docUUID.String())
+	if err != nil {
+		if errors.Is(err, storage.ErrDocumentNotFound) {
+			return nil, semerr.NewNotFoundError(err)
+		}
+		s.log(ctx).Error("selecting doc from DB failed", slog.String("id", id), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	return doc, nil
+}
+
+// Scrub при backfill дописывает суммы документам, загруженным до их появления.
+func (s *DocumentService) Scrub(ctx context.Context, backfill, withMD5 bool) (_ *models.ScrubReportDTO, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.Scrub")
+	defer tracing.End(span, &err)
+
+	refs, err := s.documentStorage.ListContentRefs(ctx)
+	if err != nil {
+		s.log(ctx).Error("failed to list content paths", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	report := &models.ScrubReportDTO{StartedAt: time.Now(), Failed: []models.ScrubMismatchDTO{}}
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		report.Checked++
+
+		sha, md, size, err := s.hashBlob(ctx, contentBlobRef(ref), withMD5)
+		if err != nil {
+			report.Failed = append(report.Failed, models.ScrubMismatchDTO{ID: ref.ID.String(), Name: ref.Name, Path: ref.Path, Expected: ref.SHA256.String, Error: err.Error()})
+			continue
+		}
+
+		if !ref.Size.Valid && backfill {
+			if err := s.storeSizes(ctx, ref, size); err != nil {
+				return nil, err
+			}
+			report.SizesBackfilled++
+		}
+
+		switch {
+		case !ref.SHA256.Valid && backfill:
+			if err := s.documentStorage.SetChecksums(ctx, ref.ID, sql.NullString{String: sha, Valid: true}, sql.NullString{String: md, Valid: md != ""}); err != nil {
+				s.log(ctx).Error("failed to store checksums", slog.String("doc_id", ref.ID.String()), slog.String("error", err.Error()))
+				return nil, semerr.NewInternalServerError(err)
+			}
+			s.cache.Delete(ctx, "document:"+ref.ID.String())
+			report.Backfilled++
+		case !ref.SHA256.Valid:
+			report.Unverified++
+		case ref.SHA256.String != sha:
+			metrics.IncIntegrityFailures()
+			s.log(ctx).Error("stored file failed integrity check", slog.String("doc_id", ref.ID.String()), slog.String("expected", ref.SHA256.String), slog.String("actual", sha))
+			report.Failed = append(report.Failed, models.ScrubMismatchDTO{ID: ref.ID.String(), Name: ref.Name, Path: ref.Path, Expected: ref.SHA256.String, Actual: sha})
+		default:
+			report.OK++
+		}
+	}
+
+	report.Duration = time.Since(report.StartedAt).Round(time.Millisecond).String()
+	s.log(ctx).Info("scrub finished", slog.Int("checked", report.Checked), slog.Int("failed", len(report.Failed)))
+	return report, nil
+}
+
+// BackfillSizes пропускает нечитаемые файлы и считает их в failed.
+func (s *DocumentService) BackfillSizes(ctx context.Context) (filled, failed int, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.BackfillSizes")
+	defer tracing.End(span, &err)
+
+	refs, err := s.documentStorage.ListContentRefs(ctx)
+	if err != nil {
+		s.log(ctx).Error("failed to list content paths", slog.String("error", err.Error()))
+		return 0, 0, semerr.NewInternalServerError(err)
+	}
+
+	for _, ref := range refs {
+		if ref.Size.Valid {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return filled, failed, err
+		}
+
+		_, _, size, err := s.hashBlob(ctx, contentBlobRef(ref), false)
+		if err != nil {
+			s.log(ctx).Warn("failed to read file", slog.String("doc_id", ref.ID.String()), slog.String("path", ref.Path), slog.String("error", err.Error()))
+			failed++
+			continue
+		}
+		if err := s.storeSizes(ctx, ref, size); err != nil {
+			return filled, failed, err
+		}
+		filled++
+	}
+
+	s.log(ctx).Info("sizes backfilled", slog.Int("filled", filled), slog.Int("failed", failed))
+	return filled, failed, nil
+}
+
+func (s *DocumentService) storeSizes(ctx context.Context, ref documentStorage.ContentRef, size int64) error {
+	storedSize, err := s.blobs.Size(ctx, ref.Path)
+	if err == nil {
+		err = s.documentStorage.SetSizes(ctx, ref.ID, size, storedSize)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to store sizes", slog.String("doc_id", ref.ID.String()), slog.String("error", err.Error()))
+		return semerr.NewInternalServerError(err)
+	}
+	s.cache.Delete(ctx, "document:"+ref.ID.String())
+	return nil
+}
+
+// hashBlob считает суммы и размер исходного содержимого файла.
+func (s *DocumentService) hashBlob(ctx context.Context, ref blobStorage.Ref, withMD5 bool) (string, string, int64, error) {
+	f, err := s.blobs.Open(ctx, ref)
+	if err != nil {
+		return "", "", 0, err
+	}
+	defer f.Close()
+
+	counter := &countingReader{r: f}
+	sha, md, err := hashReader(counter, withMD5)
+	return sha, md, counter.n, err
+}
+
+// RotateKeys не перезаписывает файлы; старый ключ можно убрать, только если в отчете нет ошибок.
+func (s *DocumentService) RotateKeys(ctx context.Context, activeKeyID string) (_ *models.KeyRotationReportDTO, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.RotateKeys")
+	defer tracing.End(span, &err)
+
+	refs, err := s.documentStorage.ListContentRefs(ctx)
+	if err != nil {
+		s.log(ctx).Error("failed to list content paths", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	report := &models.KeyRotationReportDTO{ActiveKeyID: activeKeyID, Failed: []models.KeyRotationFailureDTO{}}
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		report.Checked++
+
+		if !ref.KeyID.Valid {
+			report.Plaintext++
+			continue
+		}
+
+		keyID, wrappedKey, changed, err := s.blobs.Rewrap(contentBlobRef(ref))
+		if err != nil {
+			report.Failed = append(report.Failed, models.KeyRotationFailureDTO{ID: ref.ID.String(), Name: ref.Name, KeyID: ref.KeyID.String, Error: err.Error()})
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if err := s.documentStorage.SetWrappedKey(ctx, ref.ID, keyID, wrappedKey); err != nil {
+			s.log(ctx).Error("failed to store wrapped key", slog.String("doc_id", ref.ID.String()), slog.String("error", err.Error()))
+			return nil, semerr.NewInternalServerError(err)
+		}
+		s.cache.Delete(ctx, "document:"+ref.ID.String())
+		report.Rewrapped++
+	}
+
+	s.log(ctx).Info("key rotation finished", slog.Int("checked", report.Checked), slog.Int("rewrapped", report.Rewrapped), slog.Int("failed", len(report.Failed)))
+	return report, nil
+}
+
+func contentBlobRef(ref documentStorage.ContentRef) blobStorage.Ref {
+	return blobStorage.Ref{Path: ref.Path, KeyID: ref.KeyID.String, WrappedKey: ref.WrappedKey, Encoding: ref.Encoding.String}
+}
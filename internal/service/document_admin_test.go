@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/cache"
+	"document-server/internal/config"
+	blobStorage "document-server/internal/storage/blob"
+	documentStorage "document-server/internal/storage/document"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// sizeStorage отдает ссылки на файлы и запоминает записанные размеры.
+type sizeStorage struct {
+	DocumentStorage
+
+	refs  []documentStorage.ContentRef
+	sizes map[uuid.UUID]int64
+}
+
+func (s *sizeStorage) ListContentRefs(context.Context) ([]documentStorage.ContentRef, error) {
+	return s.refs, nil
+}
+
+func (s *sizeStorage) SetSizes(_ context.Context, id uuid.UUID, size, storedSize int64) error {
+	s.sizes[id] = storedSize
+	return nil
+}
+
+func TestBackfillSizes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	legacy := documentStorage.ContentRef{ID: uuid.New(), Path: write("legacy", "hello world")}
+	counted := documentStorage.ContentRef{ID: uuid.New(), Path: write("counted", "abc"), Size: sql.NullInt64{Int64: 3, Valid: true}}
+	missing := documentStorage.ContentRef{ID: uuid.New(), Path: filepath.Join(dir, "missing")}
+	docs := &sizeStorage{refs: []documentStorage.ContentRef{legacy, counted, missing}, sizes: map[uuid.UUID]int64{}}
+
+	s := NewDocumentService(docs, nil, discardLogger(), blobStorage.NewFileStore(dir, blobStorage.Options{}),
+		nil, nil, nil, config.IntegrityConfig{}, config.AuthConfig{}, nil, nil, cache.NewInMemoryCache(config.CacheConfig{TTL: 1}))
+
+	filled, failed, err := s.BackfillSizes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filled != 1 || failed != 1 {
+		t.Errorf("filled %d, failed %d, want 1 and 1", filled, failed)
+	}
+	if len(docs.sizes) != 1 || docs.sizes[legacy.ID] != 11 {
+		t.Errorf("stored sizes %v, want only %s = 11", docs.sizes, legacy.ID)
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// Events: lastEventID - номер последнего полученного события или пустая строка.
+func (s *DocumentService) Events(ctx context.Context, token, lastEventID string) (*Feed, error) {
+	principal, err := requireScope(ctx, s.authenticator, token, ScopeDocsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastID int64
+	if lastEventID != "" {
+		if lastID, err = strconv.ParseInt(lastEventID, 10, 64); err != nil || lastID <= 0 {
+			return nil, semerr.NewBadRequestError(errors.New("invalid Last-Event-ID"))
+		}
+	}
+
+	return s.events.Subscribe(principal.User.Login, lastID), nil
+}
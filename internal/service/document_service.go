@@ -1,19 +1,24 @@
 package service
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
 	"document-server/internal/api/models"
-	"document-server/internal/cache"
+	"document-server/internal/config"
+	"document-server/internal/logger"
+	"document-server/internal/metrics"
 	"document-server/internal/storage"
+	blobStorage "document-server/internal/storage/blob"
 	documentStorage "document-server/internal/storage/document"
+	"document-server/internal/tracing"
 	"log/slog"
 	"slices"
+	"strings"
 
 	"encoding/json"
 	"errors"
-	"os"
-	"path/filepath"
+	"io"
 	"strconv"
 	"time"
 
@@ -23,46 +28,85 @@ import (
 
 type DocumentService struct {
 	documentStorage DocumentStorage
-	userStorage     UserStorage
-	tokenStorage    TokenStorage
+	authenticator   Authenticator
 	logger          *slog.Logger
 	cache           Cache
-	storagePath     string
+	blobs           BlobStore
+	mimePolicy      *MIMEPolicy
+	compression     *CompressionPolicy
+	quotas          *QuotaService
+	audit           *AuditLog
+	events          *EventBus
+	verifyOnRead    bool
+	documentAccess  bool
 }
 
 func NewDocumentService(
-	userStorage UserStorage, documentStorage DocumentStorage,
-	tokenStorage TokenStorage,
+	documentStorage DocumentStorage,
+	authenticator Authenticator,
 	logger *slog.Logger,
-	storagePath string,
-	cache *cache.InMemoryCache,
+	blobs BlobStore,
+	mimePolicy *MIMEPolicy,
+	compression *CompressionPolicy,
+	quotas *QuotaService,
+	integrity config.IntegrityConfig,
+	auth config.AuthConfig,
+	audit *AuditLog,
+	events *EventBus,
+	cache Cache,
 ) *DocumentService {
 	return &DocumentService{
 		documentStorage: documentStorage,
-		userStorage:     userStorage,
-		tokenStorage:    tokenStorage,
+		authenticator:   authenticator,
 		logger:          logger,
-		storagePath:     storagePath,
+		blobs:           blobs,
+		mimePolicy:      mimePolicy,
+		compression:     compression,
+		quotas:          quotas,
+		audit:           audit,
+		events:          events,
+		verifyOnRead:    integrity.VerifyOnRead,
+		documentAccess:  auth.DocumentAccess,
 		cache:           cache,
 	}
 }
 
-func (s *DocumentService) UploadDocument(ctx context.Context, meta models.DocumentUploadMetaDTO, fileBytes []byte, filename string, jsonData []byte) (*models.DocumentResponseDTO, error) {
-	userToken, err := s.tokenStorage.GetByToken(ctx, meta.Token)
-	if err != nil {
-		s.logger.Error("token not found", slog.String("token", meta.Token))
-		return nil, semerr.NewBadRequestError(errors.New("token not found"))
-	}
-	user, err := s.userStorage.GetUserByID(ctx, userToken.UserID)
+func (s *DocumentService) log(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+// UploadDocument при любой ошибке удаляет файл и откатывает строку.
+func (s *DocumentService) UploadDocument(ctx context.Context, meta models.DocumentUploadMetaDTO, file io.Reader, filename string, jsonData []byte) (_ *models.DocumentResponseDTO, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.UploadDocument")
+	defer tracing.End(span, &err)
+
+	var actor, target string
+	defer func() { s.audit.Record(ctx, AuditUpload, actor, target, err) }()
+
+	principal, err := requireScope(ctx, s.authenticator, meta.Token, ScopeDocsWrite)
 	if err != nil {
-		s.logger.Error("user not found", slog.String("user_id", userToken.UserID.String()))
-		return nil, semerr.NewBadRequestError(errors.New("user not found"))
+		return nil, err
 	}
+	user := principal.User
+	actor = user.Login
 
 	if !slices.Contains(meta.Grant, user.Login) {
 		meta.Grant = append(meta.Grant, user.Login)
 	}
 
+	if meta.ExpiresAt != nil && !meta.ExpiresAt.After(time.Now()) {
+		return nil, semerr.NewBadRequestError(errors.New("expires_at must be in the future"))
+	}
+	if meta.LegalHold && !user.IsAdmin {
+		return nil, semerr.NewForbiddenError(errors.New("only administrators can place a legal hold"))
+	}
+
+	limits, err := s.quotas.CheckUpload(ctx, &user)
+	if err != nil {
+		s.log(ctx).Warn("upload rejected by quota", slog.String("user", user.Login), slog.String("error", err.Error()))
+		return nil, err
+	}
+
 	doc := documentStorage.Document{
 		ID:        uuid.New(),
 		Name:      meta.Name,
@@ -71,52 +115,114 @@ func (s *DocumentService) UploadDocument(ctx context.Context, meta models.Docume
 		IsPublic:  meta.Public,
 		CreatedAt: time.Now(),
 		GrantedTo: meta.Grant,
+		OwnerID:   uuid.NullUUID{UUID: user.ID, Valid: true},
+		LegalHold: meta.LegalHold,
+	}
+	if meta.ExpiresAt != nil {
+		doc.ExpiresAt = sql.NullTime{Time: *meta.ExpiresAt, Valid: true}
 	}
+	target = doc.ID.String()
 
+	var staged *blobStorage.StagedFile
 	if meta.File {
-		docPath := filepath.Join(s.storagePath, doc.ID.String()+"_"+filename)
-		err := os.WriteFile(docPath, fileBytes, 0644)
+		// заявленному клиентом типу верить нельзя
+		sniffer := bufio.NewReaderSize(file, sniffLen)
+		head, _ := sniffer.Peek(sniffLen)
+		doc.MimeType = resolveContentType(meta.Mime, head)
+		if doc.MimeType != meta.Mime {
+			s.log(ctx).Debug("declared MIME type replaced", slog.String("declared", meta.Mime), slog.String("detected", doc.MimeType))
+		}
+		if err := s.mimePolicy.Check(doc.MimeType, user.IsAdmin); err != nil {
+			s.log(ctx).Warn("upload rejected by MIME policy", slog.String("mime", doc.MimeType), slog.String("user", user.Login))
+			return nil, err
+		}
+		file = sniffer
+
+		// имя на диске строится только из ID
+		if name := sanitizeFilename(filename); name != "" {
+			doc.OriginalName = sql.NullString{String: name, Valid: true}
+		}
+		staged, err = s.blobs.Stage(ctx, doc.ID.String(), file, s.compression.Compress(doc.MimeType, head))
 		if err != nil {
-			s.logger.Error("failed to write file", slog.String("path", docPath), slog.String("error", err.Error()))
+			s.log(ctx).Error("failed to write file", slog.String("error", err.Error()))
 			return nil, semerr.NewInternalServerError(err)
 		}
-		doc.FilePath = sql.NullString{String: docPath, Valid: true}
+		defer func() {
+			if err != nil {
+				if abortErr := staged.Abort(); abortErr != nil {
+					s.log(ctx).Error("failed to roll back file", slog.String("path", staged.Path()), slog.String("error", abortErr.Error()))
+				}
+			}
+		}()
+		doc.FilePath = sql.NullString{String: staged.Path(), Valid: true}
+
+		if err := checkExpectedChecksums(meta, staged); err != nil {
+			s.log(ctx).Warn("upload rejected: checksum mismatch", slog.String("error", err.Error()))
+			return nil, err
+		}
+		doc.SHA256 = sql.NullString{String: staged.SHA256(), Valid: true}
+		doc.MD5 = sql.NullString{String: staged.MD5(), Valid: staged.MD5() != ""}
+		if keyID := staged.KeyID(); keyID != "" {
+			doc.KeyID = sql.NullString{String: keyID, Valid: true}
+			doc.WrappedKey = staged.WrappedKey()
+		}
+		doc.Size = sql.NullInt64{Int64: staged.Size(), Valid: true}
+		doc.StoredSize = sql.NullInt64{Int64: staged.StoredSize(), Valid: true}
+		doc.ContentEncoding = sql.NullString{String: staged.Encoding(), Valid: staged.Encoding() != ""}
 	} else {
 		if len(jsonData) > 0 {
 			if !json.Valid(jsonData) {
-				s.logger.Error("invalid JSON data")
+				s.log(ctx).Error("invalid JSON data")
 				return nil, semerr.NewBadRequestError(errors.New("invalid JSON data"))
 			}
 			doc.JSONData = sql.NullString{String: string(jsonData), Valid: true}
+			doc.Size = sql.NullInt64{Int64: int64(len(jsonData)), Valid: true}
 		}
 	}
 
-	if err := s.documentStorage.Create(ctx, doc); err != nil {
-		s.logger.Error("failed to create document", slog.String("error", err.Error()))
+	var beforeCommit func() error
+	if staged != nil {
+		beforeCommit = staged.Commit
+	}
+	if err = s.documentStorage.Create(ctx, doc, limits, beforeCommit); err != nil {
+		if errors.Is(err, storage.ErrQuotaExceeded) || errors.Is(err, storage.ErrGroupQuotaExceeded) {
+			s.log(ctx).Warn("upload rejected by quota", slog.String("user", user.Login), slog.Int64("bytes", doc.ChargedBytes()), slog.String("error", err.Error()))
+			return nil, s.quotas.rejectUpload(limits, doc.ChargedBytes(), err)
+		}
+		s.log(ctx).Error("failed to create document", slog.String("error", err.Error()))
 		return nil, semerr.NewInternalServerError(err)
 	}
 
-	s.cache.Set("document:"+doc.ID.String(), &doc)
-	s.logger.Info("document uploaded", slog.String("doc_id", doc.ID.String()), slog.String("user", user.Login))
+	uploaded := len(jsonData)
+	if staged != nil {
+		uploaded += int(staged.Size())
+	}
+	metrics.AddUploadedBytes(uploaded)
+	s.cache.Set(ctx, "document:"+doc.ID.String(), &doc)
+	s.events.Publish(documentStorage.EventCreated, &doc, doc.GrantedTo)
+	s.log(ctx).Info("document uploaded", slog.String("doc_id", doc.ID.String()), slog.String("user", user.Login))
 
 	return &models.DocumentResponseDTO{
-		JSON: json.RawMessage(doc.JSONData.String),
-		File: doc.Name,
+		JSON:       json.RawMessage(doc.JSONData.String),
+		File:       doc.Name,
+		SHA256:     doc.SHA256.String,
+		MD5:        doc.MD5.String,
+		Size:       doc.Size.Int64,
+		StoredSize: doc.StoredSize.Int64,
+		ExpiresAt:  nullTimePtr(doc.ExpiresAt),
+		LegalHold:  doc.LegalHold,
 	}, nil
 }
 
-func (s *DocumentService) ListDocuments(ctx context.Context, token, login, key, value, limitStr string) ([]models.DocumentListItemDTO, error) {
-	userToken, err := s.tokenStorage.GetByToken(ctx, token)
-	if err != nil {
-		s.logger.Error("token not found", slog.String("token", token))
-		return nil, semerr.NewBadRequestError(errors.New("token not found"))
-	}
+func (s *DocumentService) ListDocuments(ctx context.Context, token, login, key, value, limitStr string) (_ []models.DocumentListItemDTO, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.ListDocuments")
+	defer tracing.End(span, &err)
 
-	user, err := s.userStorage.GetUserByID(ctx, userToken.UserID)
+	principal, err := requireScope(ctx, s.authenticator, token, ScopeDocsRead)
 	if err != nil {
-		s.logger.Error("user not found", slog.String("user_id", userToken.UserID.String()))
-		return nil, semerr.NewBadRequestError(errors.New("user not found"))
+		return nil, err
 	}
+	user := principal.User
 
 	var limit int
 	if limitStr != "" {
@@ -135,7 +241,7 @@ func (s *DocumentService) ListDocuments(ctx context.Context, token, login, key,
 
 	docIDs, err := s.documentStorage.ListDocumentIDs(ctx, user.Login, targetLogin, key, value, limit)
 	if err != nil {
-		s.logger.Error("failed to list document IDs", slog.String("error", err.Error()))
+		s.log(ctx).Error("failed to list document IDs", slog.String("error", err.Error()))
 		return nil, semerr.NewInternalServerError(err)
 	}
 
@@ -144,16 +250,8 @@ func (s *DocumentService) ListDocuments(ctx context.Context, token, login, key,
 
 	for _, id := range docIDs {
 		cacheKey := "document:" + id
-		if cached, ok := s.cache.Get(cacheKey); ok {
-			result = append(result, models.DocumentListItemDTO{
-				ID:        cached.ID.String(),
-				Name:      cached.Name,
-				Mime:      cached.MimeType,
-				File:      cached.IsFile,
-				Public:    cached.IsPublic,
-				CreatedAt: cached.CreatedAt,
-				Grant:     cached.GrantedTo,
-			})
+		if cached, ok := s.cache.Get(ctx, cacheKey); ok {
+			result = append(result, documentListItem(cached))
 		} else {
 			idsToFetchFromDB = append(idsToFetchFromDB, id)
 		}
@@ -162,84 +260,98 @@ func (s *DocumentService) ListDocuments(ctx context.Context, token, login, key,
 	if len(idsToFetchFromDB) > 0 {
 		docs, err := s.documentStorage.GetDocumentsByIDs(ctx, idsToFetchFromDB)
 		if err != nil {
-			s.logger.Error("failed to fetch documents from DB", slog.String("error", err.Error()))
+			s.log(ctx).Error("failed to fetch documents from DB", slog.String("error", err.Error()))
 			return nil, semerr.NewInternalServerError(err)
 		}
 
 		for _, doc := range docs {
 			cacheKey := "document:" + doc.ID.String()
-			s.cache.Set(cacheKey, &doc)
-
-			result = append(result, models.DocumentListItemDTO{
-				ID:        doc.ID.String(),
-				Name:      doc.Name,
-				Mime:      doc.MimeType,
-				File:      doc.IsFile,
-				Public:    doc.IsPublic,
-				CreatedAt: doc.CreatedAt,
-				Grant:     doc.GrantedTo,
-			})
+			s.cache.Set(ctx, cacheKey, &doc)
+
+			result = append(result, documentListItem(&doc))
 		}
 	}
 
-	s.logger.Info("documents listed", slog.String("user", user.Login), slog.Int("count", len(result)))
+	s.log(ctx).Info("documents listed", slog.String("user", user.Login), slog.Int("count", len(result)))
 	return result, nil
 }
 
-func (s *DocumentService) GetDocument(ctx context.Context, id string) (*documentStorage.Document, []byte, string, error) {
-	cacheKey := "document:" + id
+// GetDocument отдает сжатый файл как есть, если клиент принимает gzip, иначе ReadSeekCloser для ServeContent.
+func (s *DocumentService) GetDocument(ctx context.Context, token, id string, acceptGzip bool) (_ *documentStorage.Document, _ io.ReadCloser, _ string, encoding string, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.GetDocument")
+	defer tracing.End(span, &err)
 
-	if docCached, ok := s.cache.Get(cacheKey); ok {
-		if docCached.IsFile {
-			data, err := os.ReadFile(docCached.FilePath.String)
-			if err != nil {
-				s.cache.Delete(cacheKey)
-				s.logger.Error("cached file not found, removed from cache", slog.String("path", docCached.FilePath.String))
-				return nil, nil, "", semerr.NewInternalServerError(err)
-			}
-			return docCached, data, docCached.MimeType, nil
-		} else {
-			return docCached, nil, "", nil
+	action, actor := AuditRead, ""
+	defer func() { s.audit.Record(ctx, action, actor, id, err) }()
+
+	doc, err := s.loadDocument(ctx, id)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	if doc.IsFile {
+		action = AuditDownload
+	}
+	if doc.Expired(time.Now()) || doc.DeletedAt.Valid {
+		s.cache.Delete(ctx, "document:"+id)
+		return nil, nil, "", "", semerr.NewBadRequestError(errors.New("document not found"))
+	}
+
+	if !doc.IsPublic && s.documentAccess {
+		principal, err := s.authorizeAccess(ctx, token, ScopeDocsRead, doc)
+		if err != nil {
+			return nil, nil, "", "", err
 		}
+		actor = principal.User.Login
 	}
 
-	doc, err := s.documentStorage.GetByID(ctx, id)
-	if err != nil {
-		s.logger.Error("document not found", slog.String("id", id))
-		return nil, nil, "", semerr.NewBadRequestError(errors.New("document not found"))
+	if !doc.IsFile || !doc.FilePath.Valid {
+		return doc, nil, "", "", nil
 	}
 
-	var data []byte
-	var mime string
+	ref := blobRef(doc)
 
-	if doc.IsFile && doc.FilePath.Valid {
-		data, err = os.ReadFile(doc.FilePath.String)
+	// отправленный ответ не отменить, поэтому сумма проверяется заранее
+	if s.verifyOnRead && doc.SHA256.Valid {
+		actual, _, _, err := s.hashBlob(ctx, ref, false)
 		if err != nil {
-			s.logger.Error("failed to read file", slog.String("path", doc.FilePath.String), slog.String("error", err.Error()))
-			return nil, nil, "", semerr.NewInternalServerError(err)
+			s.cache.Delete(ctx, "document:"+id)
+			s.log(ctx).Error("failed to read file", slog.String("path", doc.FilePath.String), slog.String("error", err.Error()))
+			return nil, nil, "", "", semerr.NewInternalServerError(err)
+		}
+		if actual != doc.SHA256.String {
+			metrics.IncIntegrityFailures()
+			s.log(ctx).Error("stored file failed integrity check", slog.String("doc_id", id), slog.String("expected", doc.SHA256.String), slog.String("actual", actual))
+			return nil, nil, "", "", semerr.NewInternalServerError(errors.New("document content failed integrity check"))
 		}
-		mime = doc.MimeType
 	}
 
-	s.cache.Set(cacheKey, &documentStorage.Document{
-		ID:        doc.ID,
-		Name:      doc.Name,
-		MimeType:  doc.MimeType,
-		IsFile:    doc.IsFile,
-		IsPublic:  doc.IsPublic,
-		CreatedAt: doc.CreatedAt,
-		GrantedTo: doc.GrantedTo,
-		FilePath:  doc.FilePath,
-		JSONData:  doc.JSONData,
-	})
+	var content io.ReadCloser
+	if ref.Encoding == "" || acceptGzip && ref.Encoding == blobStorage.EncodingGzip {
+		content, err = s.blobs.OpenStored(ctx, ref)
+		encoding = ref.Encoding
+	} else {
+		content, err = s.blobs.Open(ctx, ref)
+	}
+	if err != nil {
+		s.cache.Delete(ctx, "document:"+id)
+		s.log(ctx).Error("failed to read file", slog.String("path", doc.FilePath.String), slog.String("error", err.Error()))
+		return nil, nil, "", "", semerr.NewInternalServerError(err)
+	}
 
-	return doc, data, mime, nil
+	return doc, countDownload(content), doc.MimeType, encoding, nil
 }
 
-func (s *DocumentService) DeleteDocument(ctx context.Context, id string) error {
+// DeleteDocument перемещает документ в корзину.
+func (s *DocumentService) DeleteDocument(ctx context.Context, token, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.DeleteDocument")
+	defer tracing.End(span, &err)
+
+	var actor string
+	defer func() { s.audit.Record(ctx, AuditDelete, actor, id, err) }()
+
 	docUUID, err := uuid.Parse(id)
 	if err != nil {
-		s.logger.Error("invalid document ID", slog.String("id", id))
+		s.log(ctx).Error("invalid document ID", slog.String("id", id))
 		return semerr.NewBadRequestError(errors.New("invalid document ID"))
 	}
 
@@ -248,24 +360,168 @@ func (s *DocumentService) DeleteDocument(ctx context.Context, id string) error {
 		if err == storage.ErrDocumentNotFound {
 			return semerr.NewBadRequestError(errors.New("document not found"))
 		}
-		s.logger.Error("selecting doc from DB failed err:", err, slog.String("id", id))
+		s.log(ctx).Error("selecting doc from DB failed", slog.String("id", id), slog.String("error", err.Error()))
 		return semerr.NewBadRequestError(err)
 	}
+	if doc.DeletedAt.Valid {
+		return semerr.NewBadRequestError(errors.New("document not found"))
+	}
 
-	if doc.IsFile && doc.FilePath.Valid {
-		err := os.Remove(doc.FilePath.String)
+	if s.documentAccess {
+		principal, err := s.authorizeAccess(ctx, token, ScopeDocsDelete, doc)
 		if err != nil {
-			s.logger.Error("failed to delete file", slog.String("path", doc.FilePath.String), slog.String("error", err.Error()))
+			return err
+		}
+		actor = principal.User.Login
+	}
+
+	return s.trashDocument(ctx, doc)
+}
+
+// SetGrant доступен владельцу, а у документов без владельца - любому из granted_to.
+func (s *DocumentService) SetGrant(ctx context.Context, token, id string, grant []string) (_ *models.DocumentListItemDTO, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.SetGrant")
+	defer tracing.End(span, &err)
+
+	var actor string
+	defer func() {
+		s.audit.RecordDetail(ctx, AuditGrant, actor, id, "granted_to="+strings.Join(grant, ","), err)
+	}()
+
+	doc, err := s.loadDocument(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if doc.DeletedAt.Valid {
+		return nil, semerr.NewBadRequestError(errors.New("document not found"))
+	}
+
+	principal, err := s.authorizeAccess(ctx, token, ScopeDocsWrite, doc)
+	if err != nil {
+		return nil, err
+	}
+	user := principal.User
+	actor = user.Login
+	if doc.OwnerID.Valid && doc.OwnerID.UUID != user.ID {
+		return nil, semerr.NewForbiddenError(errors.New("only the owner can change access"))
+	}
+
+	if !slices.Contains(grant, user.Login) {
+		grant = append(grant, user.Login)
+	}
+	if err := s.documentStorage.SetGrant(ctx, doc.ID, grant); err != nil {
+		if errors.Is(err, storage.ErrDocumentNotFound) {
+			return nil, semerr.NewConflictError(errors.New("document was deleted"))
 		}
+		s.log(ctx).Error("failed to change grant", slog.String("doc_id", id), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
 	}
+	s.cache.Delete(ctx, "document:"+id)
+
+	updated := *doc
+	updated.GrantedTo = grant
+	s.events.Publish(documentStorage.EventGranted, &updated, append(slices.Clone(doc.GrantedTo), grant...))
+	s.log(ctx).Info("document access changed", slog.String("doc_id", id), slog.String("user", user.Login))
+	item := documentListItem(&updated)
+	return &item, nil
+}
 
-	s.cache.Delete("document:" + docUUID.String())
+// removeDocument удаляет файл последним: сироту уберет сверка.
+func (s *DocumentService) removeDocument(ctx context.Context, doc *documentStorage.Document) error {
+	if doc.LegalHold {
+		return semerr.NewConflictError(errors.New("document is under legal hold"))
+	}
 
-	if err := s.documentStorage.DeleteDocumentByID(ctx, docUUID); err != nil {
-		s.logger.Error("failed to delete document from DB", slog.String("id", id), slog.String("error", err.Error()))
+	if err := s.documentStorage.DeleteDocumentByID(ctx, doc.ID); err != nil {
+		if errors.Is(err, storage.ErrDocumentNotFound) {
+			// Удержание поставили или документ удалили между чтением и удалением.
+			return semerr.NewConflictError(errors.New("document is under legal hold or already deleted"))
+		}
+		s.log(ctx).Error("failed to delete document from DB", slog.String("id", doc.ID.String()), slog.String("error", err.Error()))
 		return semerr.NewInternalServerError(err)
 	}
 
-	s.logger.Info("document deleted", slog.String("id", id))
+	s.cache.Delete(ctx, "document:"+doc.ID.String())
+	if !doc.DeletedAt.Valid {
+		// Документ из корзины для подписчиков уже удален.
+		s.events.Publish(documentStorage.EventDeleted, doc, doc.GrantedTo)
+	}
+
+	if doc.IsFile && doc.FilePath.Valid {
+		err := s.blobs.Remove(ctx, doc.FilePath.String)
+		if err != nil {
+			s.log(ctx).Error("failed to delete file", slog.String("path", doc.FilePath.String), slog.String("error", err.Error()))
+		}
+	}
+
+	s.log(ctx).Info("document deleted", slog.String("id", doc.ID.String()))
 	return nil
 }
+
+// loadDocument возвращает метаданные документа из кэша или из БД, прогревая кэш.
+func (s *DocumentService) loadDocument(ctx context.Context, id string) (*documentStorage.Document, error) {
+	cacheKey := "document:" + id
+
+	if docCached, ok := s.cache.Get(ctx, cacheKey); ok {
+		return docCached, nil
+	}
+
+	loadedAt := time.Now()
+	doc, err := s.documentStorage.GetByID(ctx, id)
+	if err != nil {
+		s.log(ctx).Error("document not found", slog.String("id", id))
+		return nil, semerr.NewBadRequestError(errors.New("document not found"))
+	}
+
+	cached := *doc
+	if lc, ok := s.cache.(LoadedCache); ok {
+		lc.SetLoaded(ctx, cacheKey, &cached, loadedAt)
+	} else {
+		s.cache.Set(ctx, cacheKey, &cached)
+	}
+
+	return doc, nil
+}
+
+func (s *DocumentService) authorizeAccess(ctx context.Context, token, scope string, doc *documentStorage.Document) (*Principal, error) {
+	principal, err := requireScope(ctx, s.authenticator, token, scope)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(doc.GrantedTo, principal.User.Login) {
+		s.log(ctx).Error("access denied", slog.String("doc_id", doc.ID.String()), slog.String("user", principal.User.Login))
+		return nil, semerr.NewForbiddenError(errors.New("access denied"))
+	}
+	return principal, nil
+}
+
+func documentListItem(doc *documentStorage.Document) models.DocumentListItemDTO {
+	return models.DocumentListItemDTO{
+		ID:        doc.ID.String(),
+		Name:      doc.Name,
+		Mime:      doc.MimeType,
+		File:      doc.IsFile,
+		Public:    doc.IsPublic,
+		CreatedAt: doc.CreatedAt,
+		Grant:     doc.GrantedTo,
+		Filename:  doc.OriginalName.String,
+		SHA256:    doc.SHA256.String,
+		MD5:       doc.MD5.String,
+		Size:      doc.Size.Int64,
+		ExpiresAt: nullTimePtr(doc.ExpiresAt),
+		LegalHold: doc.LegalHold,
+		DeletedAt: nullTimePtr(doc.DeletedAt),
+	}
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// blobRef описывает содержимое документа для BlobStore.
+func blobRef(doc *documentStorage.Document) blobStorage.Ref {
+	return blobStorage.Ref{Path: doc.FilePath.String, KeyID: doc.KeyID.String, WrappedKey: doc.WrappedKey, Encoding: doc.ContentEncoding.String}
+}
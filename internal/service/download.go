@@ -0,0 +1,37 @@
+package service
+
+import (
+	"document-server/internal/metrics"
+	"io"
+)
+
+// downloadCounter считает отданные байты и добавляет их в метрику при Close.
+type downloadCounter struct {
+	io.ReadCloser
+	n int
+}
+
+func (c *downloadCounter) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += n
+	return n, err
+}
+
+func (c *downloadCounter) Close() error {
+	metrics.AddDownloadedBytes(c.n)
+	return c.ReadCloser.Close()
+}
+
+// seekableDownload оставляет Seek, чтобы содержимое можно было отдавать по частям.
+type seekableDownload struct {
+	*downloadCounter
+	io.Seeker
+}
+
+func countDownload(content io.ReadCloser) io.ReadCloser {
+	counter := &downloadCounter{ReadCloser: content}
+	if seeker, ok := content.(io.Seeker); ok {
+		return &seekableDownload{downloadCounter: counter, Seeker: seeker}
+	}
+	return counter
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"document-server/internal/api/models"
+	"document-server/internal/metrics"
+	documentStorage "document-server/internal/storage/document"
+	"slices"
+	"sync"
+	"time"
+)
+
+// EventReset: продолжить с Last-Event-ID нельзя, клиенту нужно заново запросить список.
+const EventReset = "reset"
+
+// feedBuffer - при переполнении подписчик отключается.
+const feedBuffer = 64
+
+type EventBus struct {
+	mu      sync.Mutex
+	next    int64
+	first   int64
+	history []feedEvent
+	size    int
+	feeds   map[*Feed]struct{}
+	closed  bool
+}
+
+// feedEvent: при смене доступа audience включает и старый, и новый список.
+type feedEvent struct {
+	event    models.DocumentEventDTO
+	audience []string
+	public   bool
+}
+
+func (e *feedEvent) visibleTo(login string) bool {
+	return e.public || slices.Contains(e.audience, login)
+}
+
+// Feed - подписка одного пользователя; C закрывается, когда подписка снята.
+type Feed struct {
+	C     <-chan models.DocumentEventDTO
+	ch    chan models.DocumentEventDTO
+	login string
+	bus   *EventBus
+}
+
+func NewEventBus(history int) *EventBus {
+	// номера после перезапуска больше старых
+	start := time.Now().UnixMilli() * 1000
+	return &EventBus{
+		next:  start,
+		first: start,
+		size:  history,
+		feeds: make(map[*Feed]struct{}),
+	}
+}
+
+// Publish на nil шине (docsctl) ничего не делает.
+func (b *EventBus) Publish(eventType string, doc *documentStorage.Document, audience []string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := feedEvent{
+		event: models.DocumentEventDTO{
+			ID:       b.next,
+			Type:     eventType,
+			Time:     time.Now(),
+			Document: doc.ID.String(),
+			Name:     doc.Name,
+			Grant:    slices.Clone(doc.GrantedTo),
+		},
+		audience: slices.Clone(audience),
+		public:   doc.IsPublic,
+	}
+	b.next++
+
+	b.history = append(b.history, e)
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+	b.first = b.history[0].event.ID
+
+	for feed := range b.feeds {
+		if !e.visibleTo(feed.login) {
+			continue
+		}
+		select {
+		case feed.ch <- e.event:
+		default:
+			b.remove(feed)
+		}
+	}
+}
+
+// Subscribe при lastID > 0 сначала отдает события из истории или EventReset.
+func (b *EventBus) Subscribe(login string, lastID int64) *Feed {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []models.DocumentEventDTO
+	if lastID > 0 {
+		if lastID+1 < b.first || lastID >= b.next {
+			backlog = append(backlog, models.DocumentEventDTO{Type: EventReset, Time: time.Now()})
+		} else {
+			for _, e := range b.history {
+				if e.event.ID > lastID && e.visibleTo(login) {
+					backlog = append(backlog, e.event)
+				}
+			}
+		}
+	}
+
+	ch := make(chan models.DocumentEventDTO, feedBuffer+len(backlog))
+	for _, event := range backlog {
+		ch <- event
+	}
+	feed := &Feed{C: ch, ch: ch, login: login, bus: b}
+	if b.closed {
+		close(ch)
+		return feed
+	}
+	b.feeds[feed] = struct{}{}
+	metrics.SetFeedSubscribers(len(b.feeds))
+	return feed
+}
+
+// Close нужен потому, что http.Server.Shutdown не прерывает SSE и WebSocket.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for feed := range b.feeds {
+		b.remove(feed)
+	}
+}
+
+// Close снимает подписку; повторный вызов ничего не делает.
+func (f *Feed) Close() {
+	f.bus.mu.Lock()
+	defer f.bus.mu.Unlock()
+	f.bus.remove(f)
+}
+
+func (b *EventBus) remove(feed *Feed) {
+	if _, ok := b.feeds[feed]; !ok {
+		return
+	}
+	delete(b.feeds, feed)
+	close(feed.ch)
+	metrics.SetFeedSubscribers(len(b.feeds))
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	documentStorage "document-server/internal/storage/document"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// closed сообщает, что лента закрыта и в ней не осталось событий.
+func closed(feed *Feed) bool {
+	select {
+	case _, ok := <-feed.C:
+		return !ok
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestEventBusCloseEndsFeeds(t *testing.T) {
+	bus := NewEventBus(10)
+	alice := bus.Subscribe("alice", 0)
+	bob := bus.Subscribe("bob", 0)
+
+	bus.Close()
+	if !closed(alice) || !closed(bob) {
+		t.Fatal("feeds are still open after Close")
+	}
+	// Обработчик снимает подписку и после остановки шины.
+	alice.Close()
+
+	if late := bus.Subscribe("carol", 0); !closed(late) {
+		t.Error("feed opened after Close is not closed")
+	}
+}
+
+func TestEventBusPublicDocuments(t *testing.T) {
+	bus := NewEventBus(10)
+	bob := bus.Subscribe("bob", 0)
+	defer bob.Close()
+
+	private := &documentStorage.Document{ID: uuid.New(), GrantedTo: []string{"alice"}}
+	public := &documentStorage.Document{ID: uuid.New(), GrantedTo: []string{"alice"}, IsPublic: true}
+	bus.Publish(documentStorage.EventCreated, private, private.GrantedTo)
+	bus.Publish(documentStorage.EventCreated, public, public.GrantedTo)
+
+	select {
+	case e := <-bob.C:
+		if e.Document != public.ID.String() {
+			t.Errorf("bob got an event for %s, want only the public %s", e.Document, public.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event for a public document was not delivered")
+	}
+
+	// Продолжение с Last-Event-ID отбирает события так же.
+	replay := bus.Subscribe("carol", bus.first-1)
+	defer replay.Close()
+	select {
+	case e := <-replay.C:
+		if e.Document != public.ID.String() {
+			t.Errorf("carol replayed %q for %s, want only the public %s", e.Type, e.Document, public.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("public event was not replayed")
+	}
+}
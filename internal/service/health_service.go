@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"document-server/internal/api/models"
+	"document-server/internal/cache"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+const (
+	healthStatusOK          = "ok"
+	healthStatusUnavailable = "unavailable"
+	healthStatusDraining    = "draining"
+)
+
+type CacheStatsProvider interface {
+	Stats() cache.Stats
+}
+
+type HealthService struct {
+	schemaStorage   SchemaStorage
+	cache           CacheStatsProvider
+	userService     *UserService
+	storagePath     string
+	expectedVersion uint
+	checkTimeout    time.Duration
+	startedAt       time.Time
+	draining        atomic.Bool
+}
+
+func NewHealthService(
+	schemaStorage SchemaStorage,
+	cache CacheStatsProvider,
+	userService *UserService,
+	storagePath string,
+	expectedVersion uint,
+	checkTimeout time.Duration,
+) *HealthService {
+	return &HealthService{
+		schemaStorage:   schemaStorage,
+		cache:           cache,
+		userService:     userService,
+		storagePath:     storagePath,
+		expectedVersion: expectedVersion,
+		checkTimeout:    checkTimeout,
+		startedAt:       time.Now(),
+	}
+}
+
+// SetDraining переводит readiness в состояние отказа, чтобы балансировщик перестал слать запросы на время остановки.
+func (s *HealthService) SetDraining() {
+	s.draining.Store(true)
+}
+
+func (s *HealthService) Readiness(ctx context.Context) (models.ReadinessDTO, bool) {
+	checks := map[string]models.HealthCheckDTO{
+		"database":     toCheck(s.checkDatabase(ctx)),
+		"file_storage": toCheck(s.checkFileStorage()),
+		"migrations":   toCheck(s.checkMigrations(ctx)),
+	}
+
+	result := models.ReadinessDTO{Status: healthStatusOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != healthStatusOK {
+			result.Status = healthStatusUnavailable
+		}
+	}
+	if s.draining.Load() {
+		result.Status = healthStatusDraining
+	}
+
+	return result, result.Status == healthStatusOK
+}
+
+func (s *HealthService) Status(ctx context.Context, adminToken string) (*models.StatusDTO, error) {
+	if !s.userService.IsAdmin(ctx, adminToken) {
+		return nil, semerr.NewUnauthorizedError(errors.New("invalid admin token"))
+	}
+
+	readiness, _ := s.Readiness(ctx)
+	cacheStats := s.cache.Stats()
+	pool := s.schemaStorage.PoolStats()
+
+	return &models.StatusDTO{
+		Readiness: readiness,
+		StartedAt: s.startedAt,
+		Uptime:    time.Since(s.startedAt).Round(time.Second).String(),
+		Build:     buildInfo(),
+		Cache: models.CacheStatsDTO{
+			Hits:       cacheStats.Hits,
+			Misses:     cacheStats.Misses,
+			Evictions:  cacheStats.Evictions,
+			Size:       cacheStats.Size,
+			MaxEntries: cacheStats.MaxEntries,
+			Errors:     cacheStats.Errors,
+		},
+		DBPool: models.DBPoolStatsDTO{
+			MaxOpenConnections: pool.MaxOpenConnections,
+			OpenConnections:    pool.OpenConnections,
+			InUse:              pool.InUse,
+			Idle:               pool.Idle,
+			WaitCount:          pool.WaitCount,
+			WaitDuration:       pool.WaitDuration.String(),
+		},
+	}, nil
+}
+
+func (s *HealthService) checkDatabase(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.checkTimeout)
+	defer cancel()
+	return s.schemaStorage.Ping(ctx)
+}
+
+func (s *HealthService) checkFileStorage() error {
+	f, err := os.CreateTemp(s.storagePath, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write([]byte("ok")); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *HealthService) checkMigrations(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.checkTimeout)
+	defer cancel()
+
+	version, dirty, err := s.schemaStorage.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if version != s.expectedVersion {
+		return fmt.Errorf("schema version %d, expected %d", version, s.expectedVersion)
+	}
+	return nil
+}
+
+func toCheck(err error) models.HealthCheckDTO {
+	if err != nil {
+		return models.HealthCheckDTO{Status: healthStatusUnavailable, Error: err.Error()}
+	}
+	return models.HealthCheckDTO{Status: healthStatusOK}
+}
+
+func buildInfo() models.BuildInfoDTO {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return models.BuildInfoDTO{Version: "unknown"}
+	}
+
+	result := models.BuildInfoDTO{
+		GoVersion: info.GoVersion,
+		Version:   info.Main.Version,
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			result.Revision = setting.Value
+		case "vcs.time":
+			result.BuildTime = setting.Value
+		case "vcs.modified":
+			result.Modified = setting.Value == "true"
+		}
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"database/sql"
+	"document-server/internal/logger"
+	"document-server/internal/storage"
+	userStorage "document-server/internal/storage/user"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// externalPasswordHash не совпадает ни с одним bcrypt хэшем, поэтому вход по паролю для таких пользователей невозможен.
+const externalPasswordHash = "!external"
+
+var jwtSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+type KeySet interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// JWTAuthenticatorOptions: пустые Issuer и Audience не проверяются.
+type JWTAuthenticatorOptions struct {
+	Issuer        string
+	Audience      string
+	ClockSkew     time.Duration
+	LoginClaim    string
+	AutoProvision bool
+}
+
+// JWTAuthenticator проверяет bearer JWT от внешнего провайдера и сопоставляет их с локальными пользователями.
+type JWTAuthenticator struct {
+	keys        KeySet
+	userStorage UserStorage
+	logger      *slog.Logger
+	opts        JWTAuthenticatorOptions
+	parser      *jwt.Parser
+}
+
+func NewJWTAuthenticator(keys KeySet, userStorage UserStorage, logger *slog.Logger, opts JWTAuthenticatorOptions) *JWTAuthenticator {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtSigningMethods),
+		jwt.WithLeeway(opts.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	if opts.LoginClaim == "" {
+		opts.LoginClaim = "sub"
+	}
+
+	return &JWTAuthenticator{
+		keys:        keys,
+		userStorage: userStorage,
+		logger:      logger,
+		opts:        opts,
+		parser:      jwt.NewParser(parserOpts...),
+	}
+}
+
+func (a *JWTAuthenticator) log(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, a.logger)
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		a.log(ctx).Error("invalid bearer token", slog.String("error", err.Error()))
+		return nil, semerr.NewUnauthorizedError(errors.New("invalid bearer token"))
+	}
+
+	issuer, _ := claims.GetIssuer()
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, semerr.NewUnauthorizedError(errors.New("bearer token has no subject"))
+	}
+
+	user, err := a.userStorage.GetUserByExternalID(ctx, issuer, subject)
+	if err == nil {
+		return &Principal{User: user}, nil
+	}
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		a.log(ctx).Error("failed to query user", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	if !a.opts.AutoProvision {
+		a.log(ctx).Error("external user is not provisioned", slog.String("issuer", issuer), slog.String("subject", subject))
+		return nil, semerr.NewForbiddenError(errors.New("user is not provisioned"))
+	}
+
+	user, err = a.provision(ctx, issuer, subject, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{User: user}, nil
+}
+
+func (a *JWTAuthenticator) provision(ctx context.Context, issuer, subject string, claims jwt.MapClaims) (userStorage.User, error) {
+	login, _ := claims[a.opts.LoginClaim].(string)
+	if login == "" {
+		login = subject
+	}
+	if len(login) > 255 || !hasValidLogin.MatchString(login) {
+		a.log(ctx).Error("external login is not a valid login", slog.String("login", login), slog.String("issuer", issuer))
+		return userStorage.User{}, semerr.NewBadRequestError(fmt.Errorf("claim %q must be at least 8 characters, only Latin letters and digits", a.opts.LoginClaim))
+	}
+
+	_, err := a.userStorage.GetUserByLogin(ctx, login)
+	if err == nil {
+		a.log(ctx).Error("external login collides with local account", slog.String("login", login), slog.String("issuer", issuer))
+		return userStorage.User{}, semerr.NewConflictError(errors.New("login is already taken by another account"))
+	}
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		a.log(ctx).Error("failed to query user", slog.String("error", err.Error()))
+		return userStorage.User{}, semerr.NewInternalServerError(err)
+	}
+
+	newUser := userStorage.User{
+		Login:           login,
+		PasswordHash:    externalPasswordHash,
+		ExternalIssuer:  sql.NullString{String: issuer, Valid: true},
+		ExternalSubject: sql.NullString{String: subject, Valid: true},
+	}
+	createErr := a.userStorage.Create(ctx, newUser)
+
+	// при параллельном первом входе пользователя мог создать другой запрос
+	user, err := a.userStorage.GetUserByExternalID(ctx, issuer, subject)
+	if err != nil {
+		if createErr != nil {
+			err = createErr
+		}
+		a.log(ctx).Error("failed to provision external user", slog.String("login", login), slog.String("error", err.Error()))
+		return userStorage.User{}, semerr.NewInternalServerError(err)
+	}
+
+	if createErr == nil {
+		a.log(ctx).Info("external user provisioned", slog.String("login", login), slog.String("issuer", issuer))
+	}
+	return user, nil
+}
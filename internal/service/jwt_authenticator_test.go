@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"document-server/internal/infrastructure/oidc"
+	"document-server/internal/storage"
+	userStorage "document-server/internal/storage/user"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testAudience = "document-server"
+	testKeyID    = "key-1"
+)
+
+// memoryUserStorage - пользователи в памяти для проверки сопоставления и создания.
+type memoryUserStorage struct {
+	UserStorage
+
+	mu    sync.Mutex
+	users []userStorage.User
+}
+
+func (s *memoryUserStorage) Create(_ context.Context, user userStorage.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user.ID = uuid.New()
+	s.users = append(s.users, user)
+	return nil
+}
+
+func (s *memoryUserStorage) GetUserByLogin(_ context.Context, login string) (userStorage.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Login == login {
+			return u, nil
+		}
+	}
+	return userStorage.User{}, storage.ErrUserNotFound
+}
+
+func (s *memoryUserStorage) GetUserByID(_ context.Context, id uuid.UUID) (userStorage.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return userStorage.User{}, storage.ErrUserNotFound
+}
+
+func (s *memoryUserStorage) GetUserByExternalID(_ context.Context, issuer, subject string) (userStorage.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.ExternalIssuer.String == issuer && u.ExternalSubject.String == subject {
+			return u, nil
+		}
+	}
+	return userStorage.User{}, storage.ErrUserNotFound
+}
+
+// writeJWKS сохраняет публичный ключ в JWKS файл, как его отдал бы провайдер.
+func writeJWKS(t *testing.T, key *rsa.PublicKey) string {
+	t.Helper()
+	set := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": testKeyID,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":                testIssuer,
+		"aud":                testAudience,
+		"sub":                "user-42",
+		"preferred_username": "alice2024",
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := oidc.NewFileKeySet(writeJWKS(t, &key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(name string, value any) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		wantLogin string
+		wantErr   error
+	}{
+		{
+			name:      "valid",
+			token:     signToken(t, key, testKeyID, validClaims()),
+			wantLogin: "alice2024",
+		},
+		{
+			name:    "other audience",
+			token:   signToken(t, key, testKeyID, with("aud", "another-client")),
+			wantErr: semerr.UnauthorizedError{},
+		},
+		{
+			name:    "other issuer",
+			token:   signToken(t, key, testKeyID, with("iss", "https://evil.example.com")),
+			wantErr: semerr.UnauthorizedError{},
+		},
+		{
+			name:    "expired",
+			token:   signToken(t, key, testKeyID, with("exp", time.Now().Add(-time.Hour).Unix())),
+			wantErr: semerr.UnauthorizedError{},
+		},
+		{
+			name:    "without expiry",
+			token:   signToken(t, key, testKeyID, with("exp", nil)),
+			wantErr: semerr.UnauthorizedError{},
+		},
+		{
+			name:    "unknown key ID",
+			token:   signToken(t, key, "key-2", validClaims()),
+			wantErr: semerr.UnauthorizedError{},
+		},
+		{
+			name:    "foreign key",
+			token:   signToken(t, otherKey, testKeyID, validClaims()),
+			wantErr: semerr.UnauthorizedError{},
+		},
+		{
+			name:    "without subject",
+			token:   signToken(t, key, testKeyID, with("sub", nil)),
+			wantErr: semerr.UnauthorizedError{},
+		},
+		{
+			name:    "HMAC with the public key",
+			token:   hmacToken(t, key),
+			wantErr: semerr.UnauthorizedError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &memoryUserStorage{}
+			a := NewJWTAuthenticator(keys, users, discardLogger(), JWTAuthenticatorOptions{
+				Issuer:        testIssuer,
+				Audience:      testAudience,
+				LoginClaim:    "preferred_username",
+				AutoProvision: true,
+			})
+
+			principal, err := a.Authenticate(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				if !sameErrorType(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %T", err, tt.wantErr)
+				}
+				if len(users.users) != 0 {
+					t.Error("user provisioned from a rejected token")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if principal.User.Login != tt.wantLogin || principal.User.ExternalSubject.String != "user-42" {
+				t.Errorf("principal %+v, want login %q", principal.User, tt.wantLogin)
+			}
+
+			// Повторный вход находит того же пользователя по iss и sub.
+			again, err := a.Authenticate(context.Background(), tt.token)
+			if err != nil || again.User.ID != principal.User.ID || len(users.users) != 1 {
+				t.Errorf("second login: %v, %d users", err, len(users.users))
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorProvisioning(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := oidc.NewFileKeySet(writeJWKS(t, &key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signToken(t, key, testKeyID, validClaims())
+	opts := JWTAuthenticatorOptions{Issuer: testIssuer, Audience: testAudience, LoginClaim: "preferred_username"}
+
+	t.Run("disabled", func(t *testing.T) {
+		a := NewJWTAuthenticator(keys, &memoryUserStorage{}, discardLogger(), opts)
+		if _, err := a.Authenticate(context.Background(), token); !sameErrorType(err, semerr.ForbiddenError{}) {
+			t.Errorf("got error %v, want forbidden", err)
+		}
+	})
+
+	t.Run("login taken by a local account", func(t *testing.T) {
+		users := &memoryUserStorage{users: []userStorage.User{{ID: uuid.New(), Login: "alice2024"}}}
+		opts := opts
+		opts.AutoProvision = true
+		a := NewJWTAuthenticator(keys, users, discardLogger(), opts)
+		if _, err := a.Authenticate(context.Background(), token); !sameErrorType(err, semerr.ConflictError{}) {
+			t.Errorf("got error %v, want conflict", err)
+		}
+	})
+
+	t.Run("invalid login claim", func(t *testing.T) {
+		users := &memoryUserStorage{}
+		opts := opts
+		opts.AutoProvision = true
+		a := NewJWTAuthenticator(keys, users, discardLogger(), opts)
+		for _, login := range []string{"alice", "alice@example.com", "alice 2024"} {
+			claims := validClaims()
+			claims["preferred_username"] = login
+			_, err := a.Authenticate(context.Background(), signToken(t, key, testKeyID, claims))
+			if !sameErrorType(err, semerr.BadRequestError{}) {
+				t.Errorf("login %q: got error %v, want bad request", login, err)
+			}
+		}
+		if len(users.users) != 0 {
+			t.Errorf("provisioned %d users with invalid logins", len(users.users))
+		}
+	})
+
+	t.Run("existing external user", func(t *testing.T) {
+		existing := userStorage.User{
+			ID:              uuid.New(),
+			Login:           "alice-renamed",
+			ExternalIssuer:  sql.NullString{String: testIssuer, Valid: true},
+			ExternalSubject: sql.NullString{String: "user-42", Valid: true},
+		}
+		a := NewJWTAuthenticator(keys, &memoryUserStorage{users: []userStorage.User{existing}}, discardLogger(), opts)
+		principal, err := a.Authenticate(context.Background(), token)
+		if err != nil || principal.User.ID != existing.ID {
+			t.Errorf("got %v, %v, want the existing user", principal, err)
+		}
+	})
+}
+
+// hmacToken подписывает HS256 публичным ключом - подмена алгоритма.
+func hmacToken(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(key.PublicKey.N.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// sameErrorType сообщает, что в цепочке err есть ошибка того же типа, что target.
+func sameErrorType(err, target error) bool {
+	switch target.(type) {
+	case semerr.UnauthorizedError:
+		var e semerr.UnauthorizedError
+		return errors.As(err, &e)
+	case semerr.ForbiddenError:
+		var e semerr.ForbiddenError
+		return errors.As(err, &e)
+	case semerr.ConflictError:
+		var e semerr.ConflictError
+		return errors.As(err, &e)
+	case semerr.BadRequestError:
+		var e semerr.BadRequestError
+		return errors.As(err, &e)
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"bytes"
+	"document-server/internal/config"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// sniffLen больше 512: имена внутри ZIP (word/, xl/) лежат дальше.
+const sniffLen = 8 << 10
+
+const (
+	mimeOctetStream = "application/octet-stream"
+	mimeTextPlain   = "text/plain"
+	mimeZip         = "application/zip"
+	mimeOLE         = "application/x-ole-storage"
+)
+
+var magicNumbers = []struct {
+	offset int
+	magic  []byte
+	mime   string
+}{
+	{0, []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"), mimeOLE},
+	{0, []byte("7z\xBC\xAF\x27\x1C"), "application/x-7z-compressed"},
+	{0, []byte("Rar!\x1A\x07"), "application/vnd.rar"},
+	{0, []byte("BZh"), "application/x-bzip2"},
+	{0, []byte("\xFD7zXZ\x00"), "application/x-xz"},
+	{0, []byte("\x28\xB5\x2F\xFD"), "application/zstd"},
+	{0, []byte(`{\rtf`), "application/rtf"},
+	{257, []byte("ustar"), "application/x-tar"},
+}
+
+// oleTypes по сигнатуре неотличимы, для них верим заявленному типу.
+var oleTypes = []string{
+	"application/msword",
+	"application/vnd.ms-excel",
+	"application/vnd.ms-powerpoint",
+	"application/vnd.ms-outlook",
+}
+
+func detectContentType(head []byte) string {
+	for _, m := range magicNumbers {
+		if len(head) >= m.offset+len(m.magic) && bytes.Equal(head[m.offset:m.offset+len(m.magic)], m.magic) {
+			return m.mime
+		}
+	}
+	if bytes.HasPrefix(head, []byte("PK\x03\x04")) {
+		return detectZip(head)
+	}
+	if isSVG(head) {
+		return "image/svg+xml"
+	}
+
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	switch detected {
+	case "text/xml":
+		return "application/xml"
+	case "application/x-gzip":
+		return "application/gzip"
+	}
+	return detected
+}
+
+// detectZip различает форматы поверх ZIP по именам первых записей.
+func detectZip(head []byte) string {
+	// ODF и EPUB начинаются с несжатой записи "mimetype" с самим типом
+	if len(head) > 38 && bytes.Equal(head[30:38], []byte("mimetype")) {
+		rest := head[38:]
+		if end := bytes.Index(rest, []byte("PK")); end > 0 {
+			if declared := string(rest[:end]); strings.HasPrefix(declared, "application/") {
+				return declared
+			}
+		}
+	}
+	switch {
+	case bytes.Contains(head, []byte("word/")):
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	case bytes.Contains(head, []byte("xl/")):
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case bytes.Contains(head, []byte("ppt/")):
+		return "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	case bytes.Contains(head, []byte("META-INF/MANIFEST.MF")):
+		return "application/java-archive"
+	}
+	return mimeZip
+}
+
+func isSVG(head []byte) bool {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF")))
+	if bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.HasPrefix(trimmed, []byte("<!DOCTYPE svg")) {
+		return bytes.Contains(trimmed, []byte("<svg"))
+	}
+	return bytes.HasPrefix(trimmed, []byte("<svg"))
+}
+
+// isTextual - типы, которые DetectContentType видит как text/plain.
+func isTextual(mimeType string) bool {
+	if strings.HasPrefix(mimeType, "text/") || strings.HasSuffix(mimeType, "+json") || strings.HasSuffix(mimeType, "+xml") {
+		return true
+	}
+	switch mimeType {
+	case "application/json", "application/xml", "application/javascript", "application/x-ndjson", "application/yaml", "application/sql":
+		return true
+	}
+	return false
+}
+
+// isActive - типы, которые браузер исполняет или разбирает как разметку.
+func isActive(mimeType string) bool {
+	if strings.HasSuffix(mimeType, "+xml") || strings.Contains(mimeType, "javascript") || strings.Contains(mimeType, "ecmascript") {
+		return true
+	}
+	switch mimeType {
+	case "text/html", "text/xml", "application/xml", "text/xsl", "text/css", "text/cache-manifest", "text/vtt":
+		return true
+	}
+	return false
+}
+
+// resolveContentType уточняет общий тип из содержимого заявленным, если тот совместим и не активен.
+func resolveContentType(declared string, head []byte) string {
+	detected := detectContentType(head)
+	declared, _, err := mime.ParseMediaType(declared)
+	if err != nil || declared == "" {
+		return detected
+	}
+
+	switch detected {
+	case mimeTextPlain:
+		if isTextual(declared) && !isActive(declared) {
+			return declared
+		}
+	case mimeOctetStream:
+		if !isTextual(declared) {
+			return declared
+		}
+	case mimeOLE:
+		for _, t := range oleTypes {
+			if declared == t {
+				return declared
+			}
+		}
+	case mimeZip:
+		if strings.HasPrefix(declared, "application/") && !isTextual(declared) {
+			return declared
+		}
+	}
+	return detected
+}
+
+// MIMEPolicy решает, какие типы файлов может загружать пользователь в зависимости от роли.
+type MIMEPolicy struct {
+	user  config.MIMERulesConfig
+	admin config.MIMERulesConfig
+}
+
+func NewMIMEPolicy(cfg config.MIMEPolicyConfig) *MIMEPolicy {
+	return &MIMEPolicy{user: cfg.User, admin: cfg.Admin}
+}
+
+// Check возвращает 415, если тип запрещен для роли. Запрет важнее разрешения.
+func (p *MIMEPolicy) Check(mimeType string, admin bool) error {
+	rules := p.user
+	if admin {
+		rules = p.admin
+	}
+
+	if matchMIME(rules.Deny, mimeType) {
+		return semerr.NewUnsupportedMediaTypeError(fmt.Errorf("file type %s is not allowed", mimeType))
+	}
+	if len(rules.Allow) > 0 && !matchMIME(rules.Allow, mimeType) {
+		return semerr.NewUnsupportedMediaTypeError(fmt.Errorf("file type %s is not allowed", mimeType))
+	}
+	return nil
+}
+
+func matchMIME(patterns []string, mimeType string) bool {
+	major, _, _ := strings.Cut(mimeType, "/")
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == mimeType || pattern == major+"/*" {
+			return true
+		}
+	}
+	return false
+}
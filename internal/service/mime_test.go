@@ -0,0 +1,32 @@
+package service
+
+import "testing"
+
+func TestResolveContentType(t *testing.T) {
+	text := []byte("just some words, nothing to see here\n")
+	tests := []struct {
+		name     string
+		declared string
+		head     []byte
+		want     string
+	}{
+		{"textual declared over plain text", "text/csv", text, "text/csv"},
+		{"json declared over plain text", "application/json; charset=utf-8", text, "application/json"},
+		{"html declared over plain text", "text/html", text, mimeTextPlain},
+		{"xhtml declared over plain text", "application/xhtml+xml", text, mimeTextPlain},
+		{"svg declared over plain text", "image/svg+xml", text, mimeTextPlain},
+		{"xml declared over plain text", "application/xml", text, mimeTextPlain},
+		{"javascript declared over plain text", "text/javascript", text, mimeTextPlain},
+		{"binary declared over plain text", "image/png", text, mimeTextPlain},
+		{"detected html wins", "text/plain", []byte("<html><body>hi</body></html>"), "text/html"},
+		{"declared binary over octet stream", "application/x-custom", []byte{0, 1, 2, 3}, "application/x-custom"},
+		{"no declared type", "", text, mimeTextPlain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveContentType(tt.declared, tt.head); got != tt.want {
+				t.Errorf("resolveContentType(%q) = %q, want %q", tt.declared, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/api/models"
+	"document-server/internal/config"
+	"document-server/internal/logger"
+	"document-server/internal/storage"
+	documentStorage "document-server/internal/storage/document"
+	userStorage "document-server/internal/storage/user"
+	"document-server/internal/tracing"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// InsufficientStorageError: в semerr нет 507.
+type InsufficientStorageError struct {
+	err error
+}
+
+func (e InsufficientStorageError) Error() string {
+	return e.err.Error()
+}
+
+func (e InsufficientStorageError) Unwrap() error {
+	return e.err
+}
+
+func (e InsufficientStorageError) HTTPStatus() int {
+	return http.StatusInsufficientStorage
+}
+
+type QuotaService struct {
+	documentStorage DocumentStorage
+	userStorage     UserStorage
+	authenticator   Authenticator
+	userService     *UserService
+	defaults        config.QuotaConfig
+	logger          *slog.Logger
+}
+
+func NewQuotaService(documentStorage DocumentStorage, userStorage UserStorage, authenticator Authenticator, userService *UserService, defaults config.QuotaConfig, logger *slog.Logger) *QuotaService {
+	return &QuotaService{
+		documentStorage: documentStorage,
+		userStorage:     userStorage,
+		authenticator:   authenticator,
+		userService:     userService,
+		defaults:        defaults,
+		logger:          logger,
+	}
+}
+
+func (s *QuotaService) log(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+func (s *QuotaService) Limits(ctx context.Context, user *userStorage.User) (documentStorage.Limits, error) {
+	limits := documentStorage.Limits{User: s.userQuota(user)}
+	if !user.Group.Valid {
+		return limits, nil
+	}
+	quota, err := s.groupQuota(ctx, user.Group.String)
+	if err != nil {
+		return documentStorage.Limits{}, err
+	}
+	limits.Group, limits.GroupQuota = user.Group.String, quota
+	return limits, nil
+}
+
+func (s *QuotaService) userQuota(user *userStorage.User) documentStorage.Quota {
+	quota := documentStorage.Quota{MaxBytes: int64(s.defaults.MaxBytes), MaxDocuments: int64(s.defaults.MaxDocuments)}
+	if user.QuotaBytes.Valid {
+		quota.MaxBytes = user.QuotaBytes.Int64
+	}
+	if user.QuotaDocuments.Valid {
+		quota.MaxDocuments = user.QuotaDocuments.Int64
+	}
+	return quota
+}
+
+func (s *QuotaService) groupQuota(ctx context.Context, name string) (documentStorage.Quota, error) {
+	quota := documentStorage.Quota{MaxBytes: int64(s.defaults.GroupMaxBytes), MaxDocuments: int64(s.defaults.GroupMaxDocuments)}
+	group, err := s.userStorage.GetGroup(ctx, name)
+	if err != nil {
+		return documentStorage.Quota{}, err
+	}
+	if group.QuotaBytes.Valid {
+		quota.MaxBytes = group.QuotaBytes.Int64
+	}
+	if group.QuotaDocuments.Valid {
+		quota.MaxDocuments = group.QuotaDocuments.Int64
+	}
+	return quota, nil
+}
+
+// CheckUpload отсекает загрузку до записи файла; с размером квоту проверяет вставка.
+func (s *QuotaService) CheckUpload(ctx context.Context, user *userStorage.User) (documentStorage.Limits, error) {
+	limits, err := s.Limits(ctx, user)
+	if err != nil {
+		s.log(ctx).Error("failed to load quota", slog.String("user", user.Login), slog.String("error", err.Error()))
+		return documentStorage.Limits{}, semerr.NewInternalServerError(err)
+	}
+
+	if limits.User != (documentStorage.Quota{}) {
+		usage, err := s.documentStorage.GetUsage(ctx, user.ID)
+		if err != nil {
+			s.log(ctx).Error("failed to load usage", slog.String("user", user.Login), slog.String("error", err.Error()))
+			return documentStorage.Limits{}, semerr.NewInternalServerError(err)
+		}
+		if err := checkUsage(limits.User, usage, ""); err != nil {
+			return documentStorage.Limits{}, err
+		}
+	}
+
+	if limits.Group != "" && limits.GroupQuota != (documentStorage.Quota{}) {
+		usage, err := s.documentStorage.GetGroupUsage(ctx, limits.Group)
+		if err != nil {
+			s.log(ctx).Error("failed to load group usage", slog.String("group", limits.Group), slog.String("error", err.Error()))
+			return documentStorage.Limits{}, semerr.NewInternalServerError(err)
+		}
+		if err := checkUsage(limits.GroupQuota, usage, "group "+limits.Group+" "); err != nil {
+			return documentStorage.Limits{}, err
+		}
+	}
+	return limits, nil
+}
+
+// checkUsage возвращает 507, если в квоте не осталось места ни под один документ.
+func checkUsage(quota documentStorage.Quota, usage documentStorage.Usage, owner string) error {
+	if quota.MaxDocuments > 0 && usage.Documents >= quota.MaxDocuments {
+		return InsufficientStorageError{fmt.Errorf("%sdocument quota exceeded: %d of %d documents used", owner, usage.Documents, quota.MaxDocuments)}
+	}
+	if quota.MaxBytes > 0 && usage.Bytes >= quota.MaxBytes {
+		return InsufficientStorageError{fmt.Errorf("%sstorage quota exceeded: %d of %d bytes used", owner, usage.Bytes, quota.MaxBytes)}
+	}
+	return nil
+}
+
+// rejectUpload: файл больше всей квоты - 413, иначе 507.
+func (s *QuotaService) rejectUpload(limits documentStorage.Limits, charged int64, err error) error {
+	quota, owner := limits.User, ""
+	if errors.Is(err, storage.ErrGroupQuotaExceeded) {
+		quota, owner = limits.GroupQuota, "group "+limits.Group+" "
+	}
+	if quota.MaxBytes > 0 && charged > quota.MaxBytes {
+		return semerr.NewRequestEntityTooLargeError(fmt.Errorf("document takes %d bytes, more than the whole %squota of %d bytes", charged, owner, quota.MaxBytes))
+	}
+	return InsufficientStorageError{fmt.Errorf("%sstorage quota exceeded", owner)}
+}
+
+// Usage возвращает использование и квоту владельца токена.
+func (s *QuotaService) Usage(ctx context.Context, token string) (_ *models.UsageDTO, err error) {
+	ctx, span := tracing.Start(ctx, "QuotaService.Usage")
+	defer tracing.End(span, &err)
+
+	principal, err := requireScope(ctx, s.authenticator, token, ScopeDocsRead)
+	if err != nil {
+		return nil, err
+	}
+	return s.usage(ctx, &principal.User)
+}
+
+// UserUsage возвращает использование пользователя без проверки прав (для docsctl).
+func (s *QuotaService) UserUsage(ctx context.Context, login string) (_ *models.UsageDTO, err error) {
+	ctx, span := tracing.Start(ctx, "QuotaService.UserUsage")
+	defer tracing.End(span, &err)
+
+	user, err := s.userService.lookupUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	return s.usage(ctx, user)
+}
+
+// SetQuota: NULL возвращает значение из конфигурации, nil оставляет текущее.
+func (s *QuotaService) SetQuota(ctx context.Context, login string, bytes, documents *sql.NullInt64) (_ *models.UsageDTO, err error) {
+	ctx, span := tracing.Start(ctx, "QuotaService.SetQuota")
+	defer tracing.End(span, &err)
+
+	if negativeQuota(bytes) || negativeQuota(documents) {
+		return nil, semerr.NewBadRequestError(errors.New("quota must not be negative"))
+	}
+
+	user, err := s.userService.lookupUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if bytes != nil {
+		user.QuotaBytes = *bytes
+	}
+	if documents != nil {
+		user.QuotaDocuments = *documents
+	}
+	if err := s.userStorage.SetQuota(ctx, user.ID, user.QuotaBytes, user.QuotaDocuments); err != nil {
+		s.log(ctx).Error("failed to set quota", slog.String("user", login), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("quota updated", slog.String("user", login))
+	return s.usage(ctx, user)
+}
+
+func (s *QuotaService) SetQuotaAsAdmin(ctx context.Context, adminToken, login string, req models.QuotaRequestDTO) (*models.UsageDTO, error) {
+	if !s.userService.IsAdmin(ctx, adminToken) {
+		return nil, semerr.NewUnauthorizedError(errors.New("invalid admin token"))
+	}
+	bytes, documents := nullInt64(req.MaxBytes), nullInt64(req.MaxDocuments)
+	return s.SetQuota(ctx, login, &bytes, &documents)
+}
+
+// SetGroup с пустым group убирает пользователя из группы.
+func (s *QuotaService) SetGroup(ctx context.Context, login, group string) (_ *models.UsageDTO, err error) {
+	ctx, span := tracing.Start(ctx, "QuotaService.SetGroup")
+	defer tracing.End(span, &err)
+
+	group = strings.TrimSpace(group)
+	user, err := s.userService.lookupUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	user.Group = sql.NullString{String: group, Valid: group != ""}
+	if err := s.userStorage.SetGroup(ctx, user.ID, user.Group); err != nil {
+		s.log(ctx).Error("failed to set group", slog.String("user", login), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("group updated", slog.String("user", login), slog.String("group", group))
+	return s.usage(ctx, user)
+}
+
+func (s *QuotaService) SetGroupAsAdmin(ctx context.Context, adminToken, login string, req models.GroupRequestDTO) (*models.UsageDTO, error) {
+	if !s.userService.IsAdmin(ctx, adminToken) {
+		return nil, semerr.NewUnauthorizedError(errors.New("invalid admin token"))
+	}
+	return s.SetGroup(ctx, login, req.Group)
+}
+
+// GroupUsage возвращает использование и квоту группы без проверки прав (для docsctl).
+func (s *QuotaService) GroupUsage(ctx context.Context, name string) (_ *models.GroupUsageDTO, err error) {
+	ctx, span := tracing.Start(ctx, "QuotaService.GroupUsage")
+	defer tracing.End(span, &err)
+
+	return s.groupUsage(ctx, name)
+}
+
+// SetGroupQuota, как SetQuota, но создает группу при необходимости.
+func (s *QuotaService) SetGroupQuota(ctx context.Context, name string, bytes, documents *sql.NullInt64) (_ *models.GroupUsageDTO, err error) {
+	ctx, span := tracing.Start(ctx, "QuotaService.SetGroupQuota")
+	defer tracing.End(span, &err)
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, semerr.NewBadRequestError(errors.New("group name must not be empty"))
+	}
+	if negativeQuota(bytes) || negativeQuota(documents) {
+		return nil, semerr.NewBadRequestError(errors.New("quota must not be negative"))
+	}
+
+	group, err := s.userStorage.GetGroup(ctx, name)
+	if err != nil && !errors.Is(err, storage.ErrGroupNotFound) {
+		s.log(ctx).Error("failed to load group", slog.String("group", name), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	if bytes != nil {
+		group.QuotaBytes = *bytes
+	}
+	if documents != nil {
+		group.QuotaDocuments = *documents
+	}
+
+	if err := s.userStorage.SetGroupQuota(ctx, name, group.QuotaBytes, group.QuotaDocuments); err != nil {
+		s.log(ctx).Error("failed to set group quota", slog.String("group", name), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("group quota updated", slog.String("group", name))
+	return s.groupUsage(ctx, name)
+}
+
+func (s *QuotaService) SetGroupQuotaAsAdmin(ctx context.Context, adminToken, name string, req models.QuotaRequestDTO) (*models.GroupUsageDTO, error) {
+	if !s.userService.IsAdmin(ctx, adminToken) {
+		return nil, semerr.NewUnauthorizedError(errors.New("invalid admin token"))
+	}
+	bytes, documents := nullInt64(req.MaxBytes), nullInt64(req.MaxDocuments)
+	return s.SetGroupQuota(ctx, name, &bytes, &documents)
+}
+
+func negativeQuota(q *sql.NullInt64) bool {
+	return q != nil && q.Valid && q.Int64 < 0
+}
+
+func (s *QuotaService) usage(ctx context.Context, user *userStorage.User) (*models.UsageDTO, error) {
+	usage, err := s.documentStorage.GetUsage(ctx, user.ID)
+	if err != nil {
+		s.log(ctx).Error("failed to load usage", slog.String("user", user.Login), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	quota := s.userQuota(user)
+	dto := &models.UsageDTO{
+		Login:        user.Login,
+		Bytes:        usage.Bytes,
+		Documents:    usage.Documents,
+		MaxBytes:     quota.MaxBytes,
+		MaxDocuments: quota.MaxDocuments,
+	}
+	if user.Group.Valid {
+		if dto.Group, err = s.groupUsage(ctx, user.Group.String); err != nil {
+			return nil, err
+		}
+	}
+	return dto, nil
+}
+
+func (s *QuotaService) groupUsage(ctx context.Context, name string) (*models.GroupUsageDTO, error) {
+	quota, err := s.groupQuota(ctx, name)
+	if errors.Is(err, storage.ErrGroupNotFound) {
+		return nil, semerr.NewNotFoundError(err)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to load group", slog.String("group", name), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	usage, err := s.documentStorage.GetGroupUsage(ctx, name)
+	if err != nil {
+		s.log(ctx).Error("failed to load group usage", slog.String("group", name), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	return &models.GroupUsageDTO{
+		Name:         name,
+		Bytes:        usage.Bytes,
+		Documents:    usage.Documents,
+		MaxBytes:     quota.MaxBytes,
+		MaxDocuments: quota.MaxDocuments,
+	}, nil
+}
+
+func nullInt64(v *int64) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *v, Valid: true}
+}
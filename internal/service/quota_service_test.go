@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/config"
+	"document-server/internal/storage"
+	documentStorage "document-server/internal/storage/document"
+	userStorage "document-server/internal/storage/user"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// usageStorage отдает заданное использование пользователя и группы.
+type usageStorage struct {
+	DocumentStorage
+
+	user, group documentStorage.Usage
+}
+
+func (s *usageStorage) GetUsage(context.Context, uuid.UUID) (documentStorage.Usage, error) {
+	return s.user, nil
+}
+
+func (s *usageStorage) GetGroupUsage(context.Context, string) (documentStorage.Usage, error) {
+	return s.group, nil
+}
+
+func (s *groupStorage) SetGroupQuota(_ context.Context, name string, bytes, documents sql.NullInt64) error {
+	s.group = userStorage.Group{Name: name, QuotaBytes: bytes, QuotaDocuments: documents}
+	return nil
+}
+
+// groupStorage хранит одну группу.
+type groupStorage struct {
+	UserStorage
+
+	group userStorage.Group
+}
+
+func (s *groupStorage) GetGroup(_ context.Context, name string) (userStorage.Group, error) {
+	if name != s.group.Name {
+		return userStorage.Group{}, storage.ErrGroupNotFound
+	}
+	return s.group, nil
+}
+
+func TestQuotaLimits(t *testing.T) {
+	users := &groupStorage{group: userStorage.Group{Name: "team", QuotaDocuments: sql.NullInt64{Int64: 0, Valid: true}}}
+	defaults := config.QuotaConfig{MaxBytes: 100, GroupMaxBytes: 1000, GroupMaxDocuments: 50}
+	s := NewQuotaService(&usageStorage{}, users, nil, nil, defaults, discardLogger())
+
+	limits, err := s.Limits(context.Background(), &userStorage.User{
+		QuotaDocuments: sql.NullInt64{Int64: 5, Valid: true},
+		Group:          sql.NullString{String: "team", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := documentStorage.Limits{
+		User:  documentStorage.Quota{MaxBytes: 100, MaxDocuments: 5},
+		Group: "team",
+		// Переопределение 0 снимает ограничение на число документов группы.
+		GroupQuota: documentStorage.Quota{MaxBytes: 1000},
+	}
+	if limits != want {
+		t.Errorf("limits = %+v, want %+v", limits, want)
+	}
+}
+
+func TestQuotaCheckUpload(t *testing.T) {
+	team := sql.NullString{String: "team", Valid: true}
+	tests := []struct {
+		name       string
+		group      sql.NullString
+		user       documentStorage.Usage
+		groupUsage documentStorage.Usage
+		full       bool
+	}{
+		{name: "within both", group: team, user: documentStorage.Usage{Bytes: 10}, groupUsage: documentStorage.Usage{Bytes: 500}},
+		{name: "user full", group: team, user: documentStorage.Usage{Bytes: 100}, full: true},
+		{name: "group full", group: team, user: documentStorage.Usage{Bytes: 10}, groupUsage: documentStorage.Usage{Bytes: 1000}, full: true},
+		{name: "no group", user: documentStorage.Usage{Bytes: 10}, groupUsage: documentStorage.Usage{Bytes: 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &groupStorage{group: userStorage.Group{Name: "team"}}
+			docs := &usageStorage{user: tt.user, group: tt.groupUsage}
+			s := NewQuotaService(docs, users, nil, nil, config.QuotaConfig{MaxBytes: 100, GroupMaxBytes: 1000}, discardLogger())
+
+			_, err := s.CheckUpload(context.Background(), &userStorage.User{Login: "alice", Group: tt.group})
+			if !tt.full {
+				if err != nil {
+					t.Fatalf("CheckUpload: %v", err)
+				}
+				return
+			}
+			var insufficient InsufficientStorageError
+			if !errors.As(err, &insufficient) {
+				t.Fatalf("got error %v, want 507", err)
+			}
+		})
+	}
+}
+
+func TestQuotaRejectUpload(t *testing.T) {
+	s := NewQuotaService(nil, nil, nil, nil, config.QuotaConfig{}, discardLogger())
+	limits := documentStorage.Limits{
+		User:       documentStorage.Quota{MaxBytes: 100},
+		Group:      "team",
+		GroupQuota: documentStorage.Quota{MaxBytes: 50},
+	}
+
+	// Файл в 60 байт помещается в квоту пользователя, но больше всей квоты группы.
+	var tooLarge semerr.RequestEntityTooLargeError
+	if err := s.rejectUpload(limits, 60, storage.ErrGroupQuotaExceeded); !errors.As(err, &tooLarge) {
+		t.Errorf("group rejection = %v, want 413", err)
+	}
+	var insufficient InsufficientStorageError
+	if err := s.rejectUpload(limits, 60, storage.ErrQuotaExceeded); !errors.As(err, &insufficient) {
+		t.Errorf("user rejection = %v, want 507", err)
+	}
+}
+
+func TestQuotaSetGroupQuotaKeepsOmitted(t *testing.T) {
+	users := &groupStorage{group: userStorage.Group{
+		Name:           "team",
+		QuotaBytes:     sql.NullInt64{Int64: 10, Valid: true},
+		QuotaDocuments: sql.NullInt64{Int64: 5, Valid: true},
+	}}
+	s := NewQuotaService(&usageStorage{}, users, nil, nil, config.QuotaConfig{GroupMaxDocuments: 50}, discardLogger())
+	ctx := context.Background()
+
+	if _, err := s.SetGroupQuota(ctx, "team", &sql.NullInt64{Int64: 100, Valid: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if users.group.QuotaBytes.Int64 != 100 || users.group.QuotaDocuments != (sql.NullInt64{Int64: 5, Valid: true}) {
+		t.Errorf("after -bytes only: %+v, want bytes 100 and documents 5", users.group)
+	}
+
+	usage, err := s.SetGroupQuota(ctx, "team", nil, &sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users.group.QuotaBytes.Int64 != 100 || users.group.QuotaDocuments.Valid || usage.MaxDocuments != 50 {
+		t.Errorf("after -docs default: %+v, max documents %d, want bytes 100 and the config documents", users.group, usage.MaxDocuments)
+	}
+}
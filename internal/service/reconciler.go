@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"document-server/internal/api/models"
+	"document-server/internal/logger"
+	"document-server/internal/metrics"
+	"document-server/internal/tracing"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+const (
+	ReconcileReport     = "report"
+	ReconcileQuarantine = "quarantine"
+	ReconcileDelete     = "delete"
+)
+
+type ReconcilerOptions struct {
+	StoragePath string
+	// QuarantinePath по умолчанию - подкаталог .quarantine в StoragePath.
+	QuarantinePath string
+	GracePeriod    time.Duration
+	Action         string
+	Interval       time.Duration
+}
+
+// Reconciler не трогает сирот моложе GracePeriod: файл пишется до вставки строки.
+type Reconciler struct {
+	documentStorage DocumentStorage
+	userService     *UserService
+	opts            ReconcilerOptions
+	logger          *slog.Logger
+
+	mu   sync.Mutex
+	last *models.ReconcileReportDTO
+}
+
+func NewReconciler(documentStorage DocumentStorage, userService *UserService, opts ReconcilerOptions, logger *slog.Logger) *Reconciler {
+	if opts.QuarantinePath == "" {
+		opts.QuarantinePath = filepath.Join(opts.StoragePath, ".quarantine")
+	}
+	if opts.Action == "" {
+		opts.Action = ReconcileReport
+	}
+	return &Reconciler{
+		documentStorage: documentStorage,
+		userService:     userService,
+		opts:            opts,
+		logger:          logger,
+	}
+}
+
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Reconcile(ctx, r.opts.Action, r.opts.GracePeriod); err != nil && ctx.Err() == nil {
+			r.logger.Error("reconciliation failed", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reconciler) ReconcileAsAdmin(ctx context.Context, adminToken string) (*models.ReconcileReportDTO, error) {
+	if !r.userService.IsAdmin(ctx, adminToken) {
+		return nil, semerr.NewUnauthorizedError(errors.New("invalid admin token"))
+	}
+	return r.Reconcile(ctx, r.opts.Action, r.opts.GracePeriod)
+}
+
+// LastReportAsAdmin возвращает отчет последнего прогона или nil, если прогонов еще не было.
+func (r *Reconciler) LastReportAsAdmin(ctx context.Context, adminToken string) (*models.ReconcileReportDTO, error) {
+	if !r.userService.IsAdmin(ctx, adminToken) {
+		return nil, semerr.NewUnauthorizedError(errors.New("invalid admin token"))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last, nil
+}
+
+// Reconcile: action касается только сирот, строки без файла попадают лишь в отчет.
+func (r *Reconciler) Reconcile(ctx context.Context, action string, grace time.Duration) (_ *models.ReconcileReportDTO, err error) {
+	ctx, span := tracing.Start(ctx, "Reconciler.Reconcile")
+	defer tracing.End(span, &err)
+
+	switch action {
+	case ReconcileReport, ReconcileQuarantine, ReconcileDelete:
+	default:
+		return nil, semerr.NewBadRequestError(fmt.Errorf("unknown reconcile action %q", action))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &models.ReconcileReportDTO{
+		Action:    action,
+		StartedAt: time.Now(),
+		Orphans:   []models.OrphanFileDTO{},
+		Missing:   []models.MissingContentDTO{},
+	}
+	defer func() {
+		metrics.ObserveReconcilerRun(len(report.Orphans), len(report.Missing), err)
+	}()
+
+	refs, err := r.documentStorage.ListContentRefs(ctx)
+	if err != nil {
+		r.log(ctx).Error("failed to list content paths", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	report.ScannedRows = len(refs)
+
+	referenced := make(map[string]struct{}, len(refs))
+	for _, ref := range refs {
+		referenced[filepath.Base(ref.Path)] = struct{}{}
+
+		if _, err := os.Stat(ref.Path); errors.Is(err, fs.ErrNotExist) {
+			report.Missing = append(report.Missing, models.MissingContentDTO{ID: ref.ID.String(), Name: ref.Name, Path: ref.Path})
+			r.log(ctx).Warn("document content is missing", slog.String("doc_id", ref.ID.String()), slog.String("path", ref.Path))
+		}
+	}
+
+	entries, err := os.ReadDir(r.opts.StoragePath)
+	if err != nil {
+		r.log(ctx).Error("failed to read storage directory", slog.String("path", r.opts.StoragePath), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	cutoff := report.StartedAt.Add(-grace)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		report.ScannedFiles++
+		if _, ok := referenced[entry.Name()]; ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		orphan := models.OrphanFileDTO{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()}
+		path := filepath.Join(r.opts.StoragePath, entry.Name())
+		switch action {
+		case ReconcileQuarantine:
+			if target, err := r.quarantine(path); err != nil {
+				r.log(ctx).Error("failed to quarantine orphaned file", slog.String("path", path), slog.String("error", err.Error()))
+			} else {
+				orphan.QuarantinedTo = target
+				report.FreedBytes += info.Size()
+			}
+		case ReconcileDelete:
+			if err := os.Remove(path); err != nil {
+				r.log(ctx).Error("failed to remove orphaned file", slog.String("path", path), slog.String("error", err.Error()))
+			} else {
+				orphan.Removed = true
+				report.FreedBytes += info.Size()
+			}
+		}
+		report.Orphans = append(report.Orphans, orphan)
+	}
+
+	report.Duration = time.Since(report.StartedAt).Round(time.Millisecond).String()
+	r.last = report
+
+	r.log(ctx).Info("reconciliation finished",
+		slog.String("action", action),
+		slog.Int("scanned_files", report.ScannedFiles),
+		slog.Int("scanned_rows", report.ScannedRows),
+		slog.Int("orphans", len(report.Orphans)),
+		slog.Int("missing", len(report.Missing)),
+	)
+	return report, nil
+}
+
+// quarantine переносит файл в карантинный каталог, сохраняя имя.
+func (r *Reconciler) quarantine(path string) (string, error) {
+	if err := os.MkdirAll(r.opts.QuarantinePath, 0755); err != nil {
+		return "", err
+	}
+	target := filepath.Join(r.opts.QuarantinePath, filepath.Base(path))
+	if err := os.Rename(path, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+func (r *Reconciler) log(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, r.logger)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"document-server/internal/api/models"
+	"document-server/internal/config"
+	"document-server/internal/logger"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// ReloadService возвращает остальные изменения как требующие перезапуска.
+type ReloadService struct {
+	mu          sync.Mutex
+	current     *config.Config
+	load        func() (*config.Config, error)
+	apply       func(*config.Config)
+	userService *UserService
+	logger      *slog.Logger
+}
+
+func NewReloadService(current *config.Config, load func() (*config.Config, error), apply func(*config.Config), userService *UserService, logger *slog.Logger) *ReloadService {
+	return &ReloadService{
+		current:     current,
+		load:        load,
+		apply:       apply,
+		userService: userService,
+		logger:      logger,
+	}
+}
+
+func (s *ReloadService) ReloadAsAdmin(ctx context.Context, adminToken string) (*models.ConfigReloadDTO, error) {
+	if !s.userService.IsAdmin(ctx, adminToken) {
+		return nil, semerr.NewUnauthorizedError(errors.New("invalid admin token"))
+	}
+	return s.Reload(ctx)
+}
+
+func (s *ReloadService) Reload(ctx context.Context) (*models.ConfigReloadDTO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := s.load()
+	if err != nil {
+		s.log(ctx).Error("config reload failed", slog.String("error", err.Error()))
+		return nil, semerr.NewBadRequestError(err)
+	}
+
+	merged := *s.current
+	merged.Log.Level = next.Log.Level
+	// backend, общий кэш и подписка создаются при запуске
+	merged.CacheConfig.TTL = next.CacheConfig.TTL
+	merged.CacheConfig.MaxEntries = next.CacheConfig.MaxEntries
+	merged.RateLimit = next.RateLimit
+	merged.Upload = next.Upload
+
+	result := &models.ConfigReloadDTO{
+		Applied:         config.Diff(s.current, &merged),
+		RestartRequired: config.Diff(&merged, next),
+	}
+
+	s.apply(&merged)
+	s.current = &merged
+
+	s.log(ctx).Info("config reloaded", slog.Any("applied", result.Applied))
+	if len(result.RestartRequired) > 0 {
+		s.log(ctx).Warn("config changes require restart", slog.Any("settings", result.RestartRequired))
+	}
+	return result, nil
+}
+
+func (s *ReloadService) log(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"document-server/internal/config"
+	"slices"
+	"testing"
+)
+
+func TestReloadSplitsCacheSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(c *config.Config)
+		wantApplied []string
+		wantRestart []string
+	}{
+		{
+			name:        "ttl",
+			change:      func(c *config.Config) { c.CacheConfig.TTL++ },
+			wantApplied: []string{"cache.ttl"},
+		},
+		{
+			name:        "max entries",
+			change:      func(c *config.Config) { c.CacheConfig.MaxEntries++ },
+			wantApplied: []string{"cache.max_entries"},
+		},
+		{
+			name:        "listen",
+			change:      func(c *config.Config) { c.CacheConfig.Listen = !c.CacheConfig.Listen },
+			wantRestart: []string{"cache.listen"},
+		},
+		{
+			name:        "fallback ttl",
+			change:      func(c *config.Config) { c.CacheConfig.FallbackTTL++ },
+			wantRestart: []string{"cache.fallback_ttl"},
+		},
+		{
+			name:        "backend",
+			change:      func(c *config.Config) { c.CacheConfig.Backend = "tiered" },
+			wantRestart: []string{"cache.backend"},
+		},
+		{
+			name:        "shared ttl",
+			change:      func(c *config.Config) { c.CacheConfig.Shared.TTL++ },
+			wantRestart: []string{"cache.shared.ttl"},
+		},
+		{
+			name:        "shared address",
+			change:      func(c *config.Config) { c.CacheConfig.Shared.Address = "cache:6379" },
+			wantRestart: []string{"cache.shared.address"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := config.Default()
+			next := config.Default()
+			tt.change(&next)
+
+			var applied *config.Config
+			s := NewReloadService(&current, func() (*config.Config, error) { return &next, nil },
+				func(c *config.Config) { applied = c }, nil, discardLogger())
+
+			result, err := s.Reload(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(result.Applied, tt.wantApplied) {
+				t.Errorf("applied = %q, want %q", result.Applied, tt.wantApplied)
+			}
+			if !slices.Equal(result.RestartRequired, tt.wantRestart) {
+				t.Errorf("restart required = %q, want %q", result.RestartRequired, tt.wantRestart)
+			}
+			if diff := config.Diff(&current, applied); !slices.Equal(diff, tt.wantApplied) {
+				t.Errorf("settings passed to apply differ in %q, want %q", diff, tt.wantApplied)
+			}
+		})
+	}
+}
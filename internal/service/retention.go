@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"document-server/internal/metrics"
+	documentStorage "document-server/internal/storage/document"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// Retention удаляет истекшие документы, очищает корзину и outbox, управляет legal hold.
+type Retention struct {
+	documentService *DocumentService
+	userService     *UserService
+	webhookStorage  WebhookStorage
+	opts            RetentionOptions
+	logger          *slog.Logger
+}
+
+// RetentionOptions: нулевой период отключает соответствующую очистку.
+type RetentionOptions struct {
+	Interval           time.Duration
+	BatchSize          int
+	TrashPeriod        time.Duration
+	EventPeriod        time.Duration
+	UndispatchedEvents bool
+}
+
+func NewRetention(documentService *DocumentService, userService *UserService, webhookStorage WebhookStorage, opts RetentionOptions, logger *slog.Logger) *Retention {
+	return &Retention{
+		documentService: documentService,
+		userService:     userService,
+		webhookStorage:  webhookStorage,
+		opts:            opts,
+		logger:          logger,
+	}
+}
+
+func (r *Retention) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Retention) sweep(ctx context.Context) {
+	deleted, purged, err := r.Sweep(ctx)
+	metrics.ObserveRetentionRun(deleted, purged, err)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Error("retention sweep failed", slog.String("error", err.Error()))
+		}
+		return
+	}
+	if deleted > 0 || purged > 0 {
+		r.logger.Info("retention sweep finished", slog.Int("expired", deleted), slog.Int("purged", purged))
+	}
+
+	events, err := r.PruneEvents(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Error("webhook event cleanup failed", slog.String("error", err.Error()))
+		}
+		return
+	}
+	if events > 0 {
+		r.logger.Info("webhook events cleaned up", slog.Int("events", events))
+	}
+}
+
+func (r *Retention) Sweep(ctx context.Context) (deleted, purged int, err error) {
+	deleted, err = r.documentService.DeleteExpired(ctx, r.opts.BatchSize)
+	if err != nil || r.opts.TrashPeriod <= 0 {
+		return deleted, 0, err
+	}
+	purged, err = r.documentService.PurgeTrash(ctx, r.opts.TrashPeriod, r.opts.BatchSize)
+	return deleted, purged, err
+}
+
+func (r *Retention) PruneEvents(ctx context.Context) (int, error) {
+	if r.opts.EventPeriod <= 0 {
+		return 0, nil
+	}
+	before := time.Now().Add(-r.opts.EventPeriod)
+
+	total := 0
+	for {
+		n, err := r.webhookStorage.DeleteEvents(ctx, before, r.opts.UndispatchedEvents, r.opts.BatchSize)
+		total += n
+		if err != nil || n < r.opts.BatchSize {
+			return total, err
+		}
+	}
+}
+
+func (r *Retention) SetLegalHoldAsAdmin(ctx context.Context, adminToken, id string, hold bool) (*documentStorage.Document, error) {
+	if !r.userService.IsAdmin(ctx, adminToken) {
+		return nil, semerr.NewUnauthorizedError(errors.New("invalid admin token"))
+	}
+	return r.documentService.SetLegalHold(ctx, id, hold)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// eventStorage отдает удаленные события пачками, пока не кончатся remaining.
+type eventStorage struct {
+	WebhookStorage
+
+	remaining    int
+	before       time.Time
+	undispatched bool
+	calls        int
+}
+
+func (s *eventStorage) DeleteEvents(_ context.Context, before time.Time, undispatched bool, limit int) (int, error) {
+	s.calls++
+	s.before, s.undispatched = before, undispatched
+	n := min(s.remaining, limit)
+	s.remaining -= n
+	return n, nil
+}
+
+func TestPruneEvents(t *testing.T) {
+	store := &eventStorage{remaining: 25}
+	r := NewRetention(nil, nil, store, RetentionOptions{BatchSize: 10, EventPeriod: 24 * time.Hour, UndispatchedEvents: true}, discardLogger())
+
+	start := time.Now()
+	n, err := r.PruneEvents(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := time.Now()
+	if n != 25 || store.calls != 3 {
+		t.Errorf("pruned %d events in %d batches, want 25 in 3", n, store.calls)
+	}
+	if store.before.Before(start.Add(-24*time.Hour)) || store.before.After(end.Add(-24*time.Hour)) {
+		t.Errorf("cutoff %v, want a day before the sweep", store.before)
+	}
+	if !store.undispatched {
+		t.Error("undispatched events were kept although delivery is disabled")
+	}
+}
+
+func TestPruneEventsDisabled(t *testing.T) {
+	store := &eventStorage{remaining: 5}
+	r := NewRetention(nil, nil, store, RetentionOptions{BatchSize: 10}, discardLogger())
+
+	if n, err := r.PruneEvents(context.Background()); err != nil || n != 0 || store.calls != 0 {
+		t.Errorf("PruneEvents with eventDays 0 deleted %d events in %d calls (%v)", n, store.calls, err)
+	}
+}
@@ -2,10 +2,17 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	apiKeyStorage "document-server/internal/storage/apikey"
+	auditStorage "document-server/internal/storage/audit"
+	blobStorage "document-server/internal/storage/blob"
 	documentStorage "document-server/internal/storage/document"
 	storage "document-server/internal/storage/document"
 	tokenStorage "document-server/internal/storage/token"
 	userStorage "document-server/internal/storage/user"
+	webhookStorage "document-server/internal/storage/webhook"
+	"io"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -14,24 +21,97 @@ type UserStorage interface {
 	Create(ctx context.Context, user userStorage.User) error
 	GetUserByLogin(ctx context.Context, login string) (userStorage.User, error)
 	GetUserByID(ctx context.Context, uuid uuid.UUID) (userStorage.User, error)
+	GetUserByExternalID(ctx context.Context, issuer, subject string) (userStorage.User, error)
+	List(ctx context.Context) ([]userStorage.User, error)
+	DeleteByID(ctx context.Context, id uuid.UUID) error
+	SetQuota(ctx context.Context, id uuid.UUID, bytes, documents sql.NullInt64) error
+	SetGroup(ctx context.Context, id uuid.UUID, group sql.NullString) error
+	GetGroup(ctx context.Context, name string) (userStorage.Group, error)
+	SetGroupQuota(ctx context.Context, name string, bytes, documents sql.NullInt64) error
 }
 
 type DocumentStorage interface {
-	Create(ctx context.Context, doc documentStorage.Document) error
+	Create(ctx context.Context, doc documentStorage.Document, limits documentStorage.Limits, beforeCommit func() error) error
 	GetByID(ctx context.Context, id string) (*documentStorage.Document, error)
 	GetDocumentsByIDs(ctx context.Context, ids []string) ([]storage.Document, error)
 	ListDocumentIDs(ctx context.Context, currentLogin string, filterLogin string, key string, value string, limit int) ([]string, error)
 	DeleteDocumentByID(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, login string, limit int) ([]documentStorage.Document, error)
+	ListContentRefs(ctx context.Context) ([]documentStorage.ContentRef, error)
+	SetChecksums(ctx context.Context, id uuid.UUID, sha256, md5 sql.NullString) error
+	SetSizes(ctx context.Context, id uuid.UUID, size, storedSize int64) error
+	SetWrappedKey(ctx context.Context, id uuid.UUID, keyID string, wrappedKey []byte) error
+	GetUsage(ctx context.Context, ownerID uuid.UUID) (documentStorage.Usage, error)
+	GetGroupUsage(ctx context.Context, group string) (documentStorage.Usage, error)
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]documentStorage.Document, error)
+	SetLegalHold(ctx context.Context, id uuid.UUID, hold bool) error
+	MoveToTrash(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID) error
+	ListTrash(ctx context.Context, login string, limit int) ([]documentStorage.Document, error)
+	ListTrashedBefore(ctx context.Context, before time.Time, limit int) ([]documentStorage.Document, error)
+	SetGrant(ctx context.Context, id uuid.UUID, grant []string) error
 }
 
 type TokenStorage interface {
 	Create(ctx context.Context, token tokenStorage.UserToken) error
 	GetByToken(ctx context.Context, token string) (tokenStorage.UserToken, error)
 	Delete(ctx context.Context, token string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) (int64, error)
 }
 
 type Cache interface {
-	Get(key string) (*documentStorage.Document, bool)
-	Set(key string, doc *documentStorage.Document)
-	Delete(key string)
+	Get(ctx context.Context, key string) (*documentStorage.Document, bool)
+	Set(ctx context.Context, key string, doc *documentStorage.Document)
+	Delete(ctx context.Context, key string)
+}
+
+// LoadedCache не сохраняет документ, прочитанный до инвалидации записи.
+type LoadedCache interface {
+	SetLoaded(ctx context.Context, key string, doc *documentStorage.Document, loadedAt time.Time)
+}
+
+type APIKeyStorage interface {
+	Create(ctx context.Context, key apiKeyStorage.APIKey) error
+	GetByHash(ctx context.Context, hash string) (apiKeyStorage.APIKey, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]apiKeyStorage.APIKey, error)
+	Revoke(ctx context.Context, id, userID uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+type AuditStorage interface {
+	Append(ctx context.Context, event auditStorage.Event) error
+	List(ctx context.Context, filter auditStorage.Filter) ([]auditStorage.Event, error)
+	Export(ctx context.Context, filter auditStorage.Filter, fn func(auditStorage.Event) error) error
+}
+
+type WebhookStorage interface {
+	Create(ctx context.Context, sub webhookStorage.Subscription) error
+	Get(ctx context.Context, id uuid.UUID) (webhookStorage.Subscription, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]webhookStorage.Subscription, error)
+	Delete(ctx context.Context, id, userID uuid.UUID) error
+	Dispatch(ctx context.Context, limit int) (int, error)
+	DeleteEvents(ctx context.Context, before time.Time, undispatched bool, limit int) (int, error)
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]webhookStorage.Job, error)
+	Delivered(ctx context.Context, id int64, status int) error
+	Failed(ctx context.Context, id int64, status sql.NullInt32, errText string, next time.Time, dead bool) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) ([]webhookStorage.Delivery, error)
+	Redeliver(ctx context.Context, id int64, subscriptionID uuid.UUID) error
+}
+
+type SchemaStorage interface {
+	Ping(ctx context.Context) error
+	PoolStats() sql.DBStats
+	Version(ctx context.Context) (uint, bool, error)
+}
+
+// BlobStore хранит содержимое файловых документов.
+type BlobStore interface {
+	Stage(ctx context.Context, name string, r io.Reader, compress bool) (*blobStorage.StagedFile, error)
+	Open(ctx context.Context, ref blobStorage.Ref) (io.ReadCloser, error)
+	OpenStored(ctx context.Context, ref blobStorage.Ref) (io.ReadSeekCloser, error)
+	ReadAll(ctx context.Context, ref blobStorage.Ref) ([]byte, error)
+	Size(ctx context.Context, path string) (int64, error)
+	Remove(ctx context.Context, path string) error
+	Rewrap(ref blobStorage.Ref) (keyID string, wrappedKey []byte, changed bool, err error)
 }
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"document-server/internal/metrics"
+	"log/slog"
+	"time"
+)
+
+// TokenJanitor периодически удаляет истекшие сессионные токены.
+type TokenJanitor struct {
+	tokenStorage TokenStorage
+	interval     time.Duration
+	logger       *slog.Logger
+}
+
+func NewTokenJanitor(tokenStorage TokenStorage, interval time.Duration, logger *slog.Logger) *TokenJanitor {
+	return &TokenJanitor{
+		tokenStorage: tokenStorage,
+		interval:     interval,
+		logger:       logger,
+	}
+}
+
+func (j *TokenJanitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *TokenJanitor) sweep(ctx context.Context) {
+	deleted, err := j.tokenStorage.DeleteExpired(ctx)
+	metrics.ObserveTokenJanitorRun(deleted, err)
+	if err != nil {
+		if ctx.Err() == nil {
+			j.logger.Error("failed to delete expired tokens", slog.String("error", err.Error()))
+		}
+		return
+	}
+	if deleted > 0 {
+		j.logger.Info("expired tokens deleted", slog.Int64("count", deleted))
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"document-server/internal/api/models"
+	"document-server/internal/storage"
+	documentStorage "document-server/internal/storage/document"
+	"document-server/internal/tracing"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+// trashDocument ставит deleted_at; квоту документ занимает до окончательного удаления.
+func (s *DocumentService) trashDocument(ctx context.Context, doc *documentStorage.Document) error {
+	if doc.LegalHold {
+		return semerr.NewConflictError(errors.New("document is under legal hold"))
+	}
+
+	if err := s.documentStorage.MoveToTrash(ctx, doc.ID); err != nil {
+		if errors.Is(err, storage.ErrDocumentNotFound) {
+			return semerr.NewConflictError(errors.New("document is under legal hold or already deleted"))
+		}
+		s.log(ctx).Error("failed to move document to trash", slog.String("id", doc.ID.String()), slog.String("error", err.Error()))
+		return semerr.NewInternalServerError(err)
+	}
+
+	s.cache.Delete(ctx, "document:"+doc.ID.String())
+	s.events.Publish(documentStorage.EventDeleted, doc, doc.GrantedTo)
+	s.log(ctx).Info("document moved to trash", slog.String("id", doc.ID.String()))
+	return nil
+}
+
+func (s *DocumentService) ListTrash(ctx context.Context, token, limitStr string) (_ []models.DocumentListItemDTO, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.ListTrash")
+	defer tracing.End(span, &err)
+
+	principal, err := requireScope(ctx, s.authenticator, token, ScopeDocsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	docs, err := s.documentStorage.ListTrash(ctx, principal.User.Login, limit)
+	if err != nil {
+		s.log(ctx).Error("failed to list trash", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	result := make([]models.DocumentListItemDTO, 0, len(docs))
+	for i := range docs {
+		result = append(result, documentListItem(&docs[i]))
+	}
+	return result, nil
+}
+
+func (s *DocumentService) RestoreDocument(ctx context.Context, token, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.RestoreDocument")
+	defer tracing.End(span, &err)
+
+	var actor string
+	defer func() { s.audit.Record(ctx, AuditRestore, actor, id, err) }()
+
+	doc, err := s.loadTrashed(ctx, id)
+	if err != nil {
+		return err
+	}
+	principal, err := s.authorizeAccess(ctx, token, ScopeDocsWrite, doc)
+	if err != nil {
+		return err
+	}
+	actor = principal.User.Login
+	return s.restoreDocument(ctx, doc)
+}
+
+func (s *DocumentService) PurgeDocument(ctx context.Context, token, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.PurgeDocument")
+	defer tracing.End(span, &err)
+
+	var actor string
+	defer func() { s.audit.Record(ctx, AuditPurge, actor, id, err) }()
+
+	doc, err := s.loadTrashed(ctx, id)
+	if err != nil {
+		return err
+	}
+	principal, err := s.authorizeAccess(ctx, token, ScopeDocsDelete, doc)
+	if err != nil {
+		return err
+	}
+	actor = principal.User.Login
+	return s.removeDocument(ctx, doc)
+}
+
+// AdminRestoreDocument - без проверки прав, для docsctl.
+func (s *DocumentService) AdminRestoreDocument(ctx context.Context, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.AdminRestoreDocument")
+	defer tracing.End(span, &err)
+
+	defer func() { s.audit.Record(ctx, AuditRestore, actorDocsctl, id, err) }()
+
+	doc, err := s.loadTrashed(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.restoreDocument(ctx, doc)
+}
+
+func (s *DocumentService) PurgeTrash(ctx context.Context, olderThan time.Duration, batchSize int) (purged int, err error) {
+	ctx, span := tracing.Start(ctx, "DocumentService.PurgeTrash")
+	defer tracing.End(span, &err)
+
+	for {
+		docs, err := s.documentStorage.ListTrashedBefore(ctx, time.Now().Add(-olderThan), batchSize)
+		if err != nil {
+			return purged, err
+		}
+		for i := range docs {
+			err := s.removeDocument(ctx, &docs[i])
+			var conflict semerr.ConflictError
+			if errors.As(err, &conflict) {
+				continue
+			}
+			s.audit.RecordDetail(ctx, AuditPurge, actorRetention, docs[i].ID.String(), "trash period ended", err)
+			if err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if len(docs) < batchSize {
+			return purged, nil
+		}
+	}
+}
+
+func (s *DocumentService) restoreDocument(ctx context.Context, doc *documentStorage.Document) error {
+	if err := s.documentStorage.Restore(ctx, doc.ID); err != nil {
+		if errors.Is(err, storage.ErrDocumentNotFound) {
+			return semerr.NewConflictError(errors.New("document is not in trash"))
+		}
+		s.log(ctx).Error("failed to restore document", slog.String("id", doc.ID.String()), slog.String("error", err.Error()))
+		return semerr.NewInternalServerError(err)
+	}
+
+	s.cache.Delete(ctx, "document:"+doc.ID.String())
+	s.events.Publish(documentStorage.EventRestored, doc, doc.GrantedTo)
+	s.log(ctx).Info("document restored", slog.String("id", doc.ID.String()))
+	return nil
+}
+
+// loadTrashed читает мимо кэша; документ не из корзины - 404.
+func (s *DocumentService) loadTrashed(ctx context.Context, id string) (*documentStorage.Document, error) {
+	docUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, semerr.NewBadRequestError(errors.New("invalid document ID"))
+	}
+
+	doc, err := s.documentStorage.GetByID(ctx, docUUID.String())
+	if err != nil {
+		if errors.Is(err, storage.ErrDocumentNotFound) {
+			return nil, semerr.NewNotFoundError(errors.New("document not found in trash"))
+		}
+		s.log(ctx).Error("selecting doc from DB failed", slog.String("id", id), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	if !doc.DeletedAt.Valid {
+		return nil, semerr.NewNotFoundError(errors.New("document not found in trash"))
+	}
+	return doc, nil
+}
@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"document-server/internal/logger"
 	"document-server/internal/storage"
 	tokenStorage "document-server/internal/storage/token"
 	userStorage "document-server/internal/storage/user"
+	"document-server/internal/tracing"
 	"encoding/hex"
 	"errors"
 	"log/slog"
@@ -28,76 +30,125 @@ var (
 type UserService struct {
 	userStorage  UserStorage
 	tokenStorage TokenStorage
+	audit        *AuditLog
 	logger       *slog.Logger
 	adminToken   string
 }
 
-func NewUserService(userStorage UserStorage, tokenStorage TokenStorage, logger *slog.Logger, adminToken string) *UserService {
+func NewUserService(userStorage UserStorage, tokenStorage TokenStorage, audit *AuditLog, logger *slog.Logger, adminToken string) *UserService {
 	return &UserService{
 		userStorage:  userStorage,
 		tokenStorage: tokenStorage,
+		audit:        audit,
 		adminToken:   adminToken,
 		logger:       logger,
 	}
 }
 
-func (s *UserService) RegisterUser(ctx context.Context, login, password, token string) error {
-	if !hasValidLogin.MatchString(login) {
-		return semerr.NewBadRequestError(errors.New("login must be at least 8 characters, only Latin letters and digits"))
-	}
+func (s *UserService) log(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
 
-	if !hasMin8Chars.MatchString(password) ||
-		!hasUpperAndLower.MatchString(password) ||
-		!hasNumber.MatchString(password) ||
-		!hasSymbol.MatchString(password) {
-		return semerr.NewBadRequestError(errors.New("password must be at least 8 characters, with upper and lower case letters, a number, and a symbol"))
+func (s *UserService) RegisterUser(ctx context.Context, login, password, token string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.RegisterUser")
+	defer tracing.End(span, &err)
+
+	var actor string
+	defer func() { s.audit.Record(ctx, AuditRegister, actor, login, err) }()
+
+	if err := validateCredentials(login, password); err != nil {
+		return err
 	}
 
-	if !s.ValidateAdminToken(token) {
+	actor, ok := s.adminActor(ctx, token)
+	if !ok {
 		return semerr.NewUnauthorizedError(errors.New("invalid admin token"))
 	}
 
+	_, err = s.createUser(ctx, login, password, false)
+	return err
+}
+
+// CreateUser не проверяет admin токен, его вызывает docsctl.
+func (s *UserService) CreateUser(ctx context.Context, login, password string, admin bool) (_ *userStorage.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer tracing.End(span, &err)
+
+	defer func() { s.audit.Record(ctx, AuditRegister, actorDocsctl, login, err) }()
+
+	if err := validateCredentials(login, password); err != nil {
+		return nil, err
+	}
+	return s.createUser(ctx, login, password, admin)
+}
+
+func (s *UserService) createUser(ctx context.Context, login, password string, admin bool) (*userStorage.User, error) {
 	_, err := s.userStorage.GetUserByLogin(ctx, login)
 	if err == nil {
-		return semerr.NewBadRequestError(errors.New("user with this login already exists"))
+		return nil, semerr.NewBadRequestError(errors.New("user with this login already exists"))
 	}
 
 	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
-		s.logger.Error("failed to query user", slog.String("error", err.Error()))
-		return semerr.NewInternalServerError(err)
+		s.log(ctx).Error("failed to query user", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
 	}
 
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		s.logger.Error("failed to hash password", slog.String("error", err.Error()))
-		return semerr.NewInternalServerError(err)
+		s.log(ctx).Error("failed to hash password", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
 	}
 
-	newUser := userStorage.User{Login: login, PasswordHash: string(passwordHash)}
+	newUser := userStorage.User{Login: login, PasswordHash: string(passwordHash), IsAdmin: admin}
 	if err := s.userStorage.Create(ctx, newUser); err != nil {
-		s.logger.Error("failed to create user", slog.String("error", err.Error()))
-		return semerr.NewInternalServerError(err)
+		s.log(ctx).Error("failed to create user", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	created, err := s.userStorage.GetUserByLogin(ctx, login)
+	if err != nil {
+		s.log(ctx).Error("failed to reload created user", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("user successfully registered", slog.String("login", login), slog.Bool("admin", admin))
+	return &created, nil
+}
+
+func validateCredentials(login, password string) error {
+	if !hasValidLogin.MatchString(login) {
+		return semerr.NewBadRequestError(errors.New("login must be at least 8 characters, only Latin letters and digits"))
 	}
 
-	s.logger.Info("user successfully registered", slog.String("login", login))
+	if !hasMin8Chars.MatchString(password) ||
+		!hasUpperAndLower.MatchString(password) ||
+		!hasNumber.MatchString(password) ||
+		!hasSymbol.MatchString(password) {
+		return semerr.NewBadRequestError(errors.New("password must be at least 8 characters, with upper and lower case letters, a number, and a symbol"))
+	}
 	return nil
 }
 
-func (s *UserService) Authenticate(ctx context.Context, login, password string) (string, error) {
+func (s *UserService) Authenticate(ctx context.Context, login, password string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Authenticate")
+	defer tracing.End(span, &err)
+
+	defer func() { s.audit.Record(ctx, AuditLogin, login, login, err) }()
+
 	user, err := s.userStorage.GetUserByLogin(ctx, login)
 	if err != nil {
-		s.logger.Error("authentication failed: user not found", slog.String("login", login))
+		s.log(ctx).Error("authentication failed: user not found", slog.String("login", login))
 		return "", semerr.NewBadRequestError(errors.New("invalid credentials"))
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		s.logger.Error("authentication failed: incorrect password", slog.String("login", login))
+		s.log(ctx).Error("authentication failed: incorrect password", slog.String("login", login))
 		return "", semerr.NewBadRequestError(errors.New("invalid credentials"))
 	}
 
 	tokenBytes := make([]byte, 16)
 	if _, err := rand.Read(tokenBytes); err != nil {
-		s.logger.Error("failed to generate token", slog.String("error", err.Error()))
+		s.log(ctx).Error("failed to generate token", slog.String("error", err.Error()))
 		return "", semerr.NewInternalServerError(err)
 	}
 	token := hex.EncodeToString(tokenBytes)
@@ -109,35 +160,134 @@ func (s *UserService) Authenticate(ctx context.Context, login, password string)
 	}
 
 	if err := s.tokenStorage.Create(ctx, userToken); err != nil {
-		s.logger.Error("failed to store token", slog.String("error", err.Error()))
+		s.log(ctx).Error("failed to store token", slog.String("error", err.Error()))
 		return "", semerr.NewInternalServerError(err)
 	}
 
-	s.logger.Info("user authenticated", slog.String("login", login), slog.String("user_id", user.ID.String()))
+	s.log(ctx).Info("user authenticated", slog.String("login", login), slog.String("user_id", user.ID.String()))
 	return token, nil
 }
 
-func (s *UserService) Logout(ctx context.Context, token string) error {
+func (s *UserService) Logout(ctx context.Context, token string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Logout")
+	defer tracing.End(span, &err)
+
+	var actor string
+	defer func() { s.audit.Record(ctx, AuditLogout, actor, actor, err) }()
+
 	userToken, err := s.tokenStorage.GetByToken(ctx, token)
 	if err != nil {
-		s.logger.Error("logout failed: token not found", slog.String("token", token))
+		s.log(ctx).Error("logout failed: token not found", slog.String("token", token))
 		return semerr.NewBadRequestError(errors.New("invalid token"))
 	}
 
 	if userToken.UserID == uuid.Nil {
-		s.logger.Error("logout failed: invalid token", slog.String("token", token))
+		s.log(ctx).Error("logout failed: invalid token", slog.String("token", token))
 		return semerr.NewBadRequestError(errors.New("invalid token"))
 	}
+	actor = userToken.UserID.String()
+	if user, err := s.userStorage.GetUserByID(ctx, userToken.UserID); err == nil {
+		actor = user.Login
+	}
 
 	if err := s.tokenStorage.Delete(ctx, token); err != nil {
-		s.logger.Error("failed to delete token", slog.String("token", token), slog.String("error", err.Error()))
+		s.log(ctx).Error("failed to delete token", slog.String("token", token), slog.String("error", err.Error()))
 		return semerr.NewInternalServerError(err)
 	}
 
-	s.logger.Info("user logged out", slog.String("user_id", userToken.UserID.String()))
+	s.log(ctx).Info("user logged out", slog.String("user_id", userToken.UserID.String()))
 	return nil
 }
 
 func (s *UserService) ValidateAdminToken(token string) bool {
 	return token == s.adminToken && s.adminToken != ""
 }
+
+// IsAdmin принимает admin токен из конфига или сессионный токен пользователя с флагом is_admin.
+func (s *UserService) IsAdmin(ctx context.Context, token string) bool {
+	_, ok := s.adminActor(ctx, token)
+	return ok
+}
+
+// adminActor - то же, что IsAdmin, но еще возвращает, кто действует, для журнала аудита.
+func (s *UserService) adminActor(ctx context.Context, token string) (string, bool) {
+	if s.ValidateAdminToken(token) {
+		return actorAdminToken, true
+	}
+	if token == "" {
+		return "", false
+	}
+
+	userToken, err := s.tokenStorage.GetByToken(ctx, token)
+	if err != nil || time.Now().After(userToken.ExpiresAt) {
+		return "", false
+	}
+	user, err := s.userStorage.GetUserByID(ctx, userToken.UserID)
+	if err != nil || !user.IsAdmin {
+		return "", false
+	}
+	return user.Login, true
+}
+
+func (s *UserService) ListUsers(ctx context.Context) (_ []userStorage.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.ListUsers")
+	defer tracing.End(span, &err)
+
+	users, err := s.userStorage.List(ctx)
+	if err != nil {
+		s.log(ctx).Error("failed to list users", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	return users, nil
+}
+
+// DeleteUser не удаляет документы: они принадлежат granted_to.
+func (s *UserService) DeleteUser(ctx context.Context, login string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer tracing.End(span, &err)
+
+	user, err := s.lookupUser(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userStorage.DeleteByID(ctx, user.ID); err != nil {
+		s.log(ctx).Error("failed to delete user", slog.String("login", login), slog.String("error", err.Error()))
+		return semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("user deleted", slog.String("login", login))
+	return nil
+}
+
+// RevokeUserTokens удаляет все сессионные токены пользователя.
+func (s *UserService) RevokeUserTokens(ctx context.Context, login string) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.RevokeUserTokens")
+	defer tracing.End(span, &err)
+
+	user, err := s.lookupUser(ctx, login)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := s.tokenStorage.DeleteByUserID(ctx, user.ID)
+	if err != nil {
+		s.log(ctx).Error("failed to revoke tokens", slog.String("login", login), slog.String("error", err.Error()))
+		return 0, semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("user tokens revoked", slog.String("login", login), slog.Int64("count", revoked))
+	return revoked, nil
+}
+
+func (s *UserService) lookupUser(ctx context.Context, login string) (*userStorage.User, error) {
+	user, err := s.userStorage.GetUserByLogin(ctx, login)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, semerr.NewNotFoundError(err)
+		}
+		s.log(ctx).Error("failed to query user", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+	return &user, nil
+}
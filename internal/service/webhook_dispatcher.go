@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"document-server/internal/api/models"
+	"document-server/internal/metrics"
+	webhookStorage "document-server/internal/storage/webhook"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Подпись - HMAC-SHA256 от "<timestamp>.<тело>" на секрете подписки.
+const (
+	headerWebhookEvent     = "X-Webhook-Event"
+	headerWebhookDelivery  = "X-Webhook-Delivery"
+	headerWebhookTimestamp = "X-Webhook-Timestamp"
+	headerWebhookSignature = "X-Webhook-Signature"
+)
+
+// webhookWorkers - сколько запросов к подписчикам выполняется одновременно.
+const webhookWorkers = 8
+
+// WebhookDispatcher после MaxAttempts попыток помечает доставку мертвой.
+type WebhookDispatcher struct {
+	storage WebhookStorage
+	client  *http.Client
+	opts    WebhookOptions
+	logger  *slog.Logger
+}
+
+// WebhookOptions: AllowPrivate разрешает loopback и частные сети.
+type WebhookOptions struct {
+	Interval     time.Duration
+	BatchSize    int
+	Timeout      time.Duration
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	AllowPrivate bool
+}
+
+func NewWebhookDispatcher(storage WebhookStorage, opts WebhookOptions, logger *slog.Logger) *WebhookDispatcher {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = denyPrivateAddress
+	}
+
+	return &WebhookDispatcher{
+		storage: storage,
+		client: &http.Client{
+			Timeout: opts.Timeout,
+			// Прокси из окружения обошел бы проверку адреса.
+			Transport: &http.Transport{Proxy: nil, DialContext: dialer.DialContext},
+			// Перенаправление считается ошибкой: иначе можно увести запрос на внутренний адрес.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		d.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *WebhookDispatcher) sweep(ctx context.Context) {
+	err := d.Sweep(ctx)
+	metrics.ObserveWebhookRun(err)
+	if err != nil && ctx.Err() == nil {
+		d.logger.Error("webhook sweep failed", slog.String("error", err.Error()))
+	}
+}
+
+// Sweep раскладывает новые события по подпискам и выполняет один проход доставки.
+func (d *WebhookDispatcher) Sweep(ctx context.Context) error {
+	for {
+		n, err := d.storage.Dispatch(ctx, d.opts.BatchSize)
+		if err != nil {
+			return fmt.Errorf("dispatch events: %w", err)
+		}
+		if n < d.opts.BatchSize {
+			break
+		}
+	}
+
+	// Доставка арендуется на время, за которое все задания пачки точно завершатся.
+	lease := d.opts.Timeout * time.Duration(d.opts.BatchSize/webhookWorkers+2)
+	jobs, err := d.storage.Claim(ctx, d.opts.BatchSize, lease)
+	if err != nil {
+		return fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	sem := make(chan struct{}, webhookWorkers)
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			d.deliver(ctx, job)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, job webhookStorage.Job) {
+	status, err := d.send(ctx, job)
+	if ctx.Err() != nil {
+		// Сервер останавливается: попытку не засчитываем, доставка повторится после аренды.
+		return
+	}
+
+	log := d.logger.With(slog.Int64("delivery_id", job.ID), slog.String("url", job.URL))
+	if err == nil {
+		if err := d.storage.Delivered(ctx, job.ID, status); err != nil {
+			log.Error("failed to mark webhook delivered", slog.String("error", err.Error()))
+		}
+		metrics.ObserveWebhookDelivery(webhookStorage.StatusDelivered)
+		return
+	}
+
+	dead := job.Attempts+1 >= d.opts.MaxAttempts
+	lastStatus := sql.NullInt32{Int32: int32(status), Valid: status != 0}
+	if err := d.storage.Failed(ctx, job.ID, lastStatus, truncate(err.Error(), 500), time.Now().Add(d.backoff(job.Attempts)), dead); err != nil {
+		log.Error("failed to record webhook failure", slog.String("error", err.Error()))
+	}
+
+	if dead {
+		metrics.ObserveWebhookDelivery(webhookStorage.StatusDead)
+		log.Warn("webhook delivery is dead", slog.Int("attempts", job.Attempts+1), slog.String("error", err.Error()))
+		return
+	}
+	metrics.ObserveWebhookDelivery("retry")
+	log.Info("webhook delivery failed, will retry", slog.Int("attempts", job.Attempts+1), slog.String("error", err.Error()))
+}
+
+// send выполняет запрос и возвращает код ответа; успехом считается только 2xx.
+func (d *WebhookDispatcher) send(ctx context.Context, job webhookStorage.Job) (int, error) {
+	body, err := json.Marshal(models.WebhookPayloadDTO{
+		ID:         job.EventID,
+		Type:       job.EventType,
+		OccurredAt: job.OccurredAt,
+		Document: models.WebhookPayloadDocumentDTO{
+			ID:    job.DocumentID.String(),
+			Name:  job.Name,
+			Grant: job.GrantedTo,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "document-server-webhook")
+	req.Header.Set(headerWebhookEvent, job.EventType)
+	req.Header.Set(headerWebhookDelivery, strconv.FormatInt(job.ID, 10))
+	req.Header.Set(headerWebhookTimestamp, timestamp)
+	req.Header.Set(headerWebhookSignature, "sha256="+webhookSignature(job.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	if attempts >= 30 {
+		return d.opts.BackoffMax
+	}
+	delay := d.opts.BackoffBase << attempts
+	if delay <= 0 || delay > d.opts.BackoffMax {
+		return d.opts.BackoffMax
+	}
+	return delay
+}
+
+func webhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// denyPrivateAddress проверяет адрес после DNS, поэтому имя внутренней сети не пройдет.
+func denyPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsUnspecified() || addr.IsMulticast() || addr.IsInterfaceLocalMulticast() {
+		return errors.New("webhook address " + addr.String() + " is not public")
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
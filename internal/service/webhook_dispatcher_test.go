@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"document-server/internal/storage"
+	userStorage "document-server/internal/storage/user"
+	webhookStorage "document-server/internal/storage/webhook"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// staticAuthenticator принимает любой токен как principal.
+type staticAuthenticator struct {
+	principal *Principal
+}
+
+func (a staticAuthenticator) Authenticate(context.Context, string) (*Principal, error) {
+	return a.principal, nil
+}
+
+// fakeWebhookStorage хранит одну подписку и ее доставки в памяти.
+type fakeWebhookStorage struct {
+	WebhookStorage
+
+	mu         sync.Mutex
+	sub        webhookStorage.Subscription
+	deliveries map[int64]*webhookStorage.Delivery
+}
+
+func newFakeWebhookStorage(url string) *fakeWebhookStorage {
+	return &fakeWebhookStorage{
+		sub: webhookStorage.Subscription{
+			ID:     uuid.New(),
+			UserID: uuid.New(),
+			URL:    url,
+			Secret: "s3cret",
+		},
+		deliveries: map[int64]*webhookStorage.Delivery{
+			1: {ID: 1, EventID: 10, Status: webhookStorage.StatusPending, NextAttemptAt: time.Now()},
+		},
+	}
+}
+
+func (s *fakeWebhookStorage) delivery(id int64) webhookStorage.Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.deliveries[id]
+}
+
+func (s *fakeWebhookStorage) Get(_ context.Context, id uuid.UUID) (webhookStorage.Subscription, error) {
+	if id != s.sub.ID {
+		return webhookStorage.Subscription{}, storage.ErrWebhookNotFound
+	}
+	return s.sub, nil
+}
+
+func (s *fakeWebhookStorage) Dispatch(context.Context, int) (int, error) {
+	return 0, nil
+}
+
+func (s *fakeWebhookStorage) Claim(_ context.Context, limit int, lease time.Duration) ([]webhookStorage.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jobs []webhookStorage.Job
+	now := time.Now()
+	for _, d := range s.deliveries {
+		if d.Status != webhookStorage.StatusPending || d.NextAttemptAt.After(now) || len(jobs) == limit {
+			continue
+		}
+		d.NextAttemptAt = now.Add(lease)
+		jobs = append(jobs, webhookStorage.Job{
+			ID:         d.ID,
+			Attempts:   d.Attempts,
+			URL:        s.sub.URL,
+			Secret:     s.sub.Secret,
+			EventID:    d.EventID,
+			EventType:  "document.created",
+			DocumentID: uuid.New(),
+			Name:       "report.pdf",
+			GrantedTo:  []string{"alice"},
+			OccurredAt: now,
+		})
+	}
+	return jobs, nil
+}
+
+func (s *fakeWebhookStorage) Delivered(_ context.Context, id int64, status int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id]
+	d.Status = webhookStorage.StatusDelivered
+	d.Attempts++
+	d.LastStatus = sql.NullInt32{Int32: int32(status), Valid: true}
+	return nil
+}
+
+func (s *fakeWebhookStorage) Failed(_ context.Context, id int64, status sql.NullInt32, errText string, next time.Time, dead bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id]
+	d.Status = webhookStorage.StatusPending
+	if dead {
+		d.Status = webhookStorage.StatusDead
+	}
+	d.Attempts++
+	d.LastStatus = status
+	d.LastError = sql.NullString{String: errText, Valid: true}
+	d.NextAttemptAt = next
+	return nil
+}
+
+func (s *fakeWebhookStorage) Redeliver(_ context.Context, id int64, subscriptionID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.deliveries[id]
+	if !ok || subscriptionID != s.sub.ID {
+		return storage.ErrWebhookNotFound
+	}
+	d.Status = webhookStorage.StatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+	d.LastError = sql.NullString{}
+	return nil
+}
+
+func testWebhookOptions() WebhookOptions {
+	return WebhookOptions{
+		Interval:     time.Second,
+		BatchSize:    10,
+		Timeout:      time.Second,
+		MaxAttempts:  3,
+		BackoffBase:  time.Millisecond,
+		BackoffMax:   4 * time.Millisecond,
+		AllowPrivate: true,
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{header: r.Header.Clone(), body: body}
+	}))
+	defer server.Close()
+
+	store := newFakeWebhookStorage(server.URL)
+	if err := NewWebhookDispatcher(store, testWebhookOptions(), discardLogger()).Sweep(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-received
+	timestamp := req.header.Get(headerWebhookTimestamp)
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		t.Fatalf("timestamp %q: %v", timestamp, err)
+	}
+	mac := hmac.New(sha256.New, []byte(store.sub.Secret))
+	mac.Write([]byte(timestamp + "." + string(req.body)))
+	if got, want := req.header.Get(headerWebhookSignature), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := req.header.Get(headerWebhookEvent); got != "document.created" {
+		t.Errorf("event header = %q", got)
+	}
+	if got := req.header.Get(headerWebhookDelivery); got != "1" {
+		t.Errorf("delivery header = %q", got)
+	}
+
+	var payload struct {
+		ID   int64  `json:"id"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(req.body, &payload); err != nil || payload.ID != 10 || payload.Type != "document.created" {
+		t.Errorf("payload = %s (%v)", req.body, err)
+	}
+}
+
+func TestWebhookResponseStatus(t *testing.T) {
+	tests := []struct {
+		status     int
+		wantStatus string
+	}{
+		{http.StatusOK, webhookStorage.StatusDelivered},
+		{http.StatusNoContent, webhookStorage.StatusDelivered},
+		{http.StatusFound, webhookStorage.StatusPending},
+		{http.StatusNotFound, webhookStorage.StatusPending},
+		{http.StatusInternalServerError, webhookStorage.StatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "http://169.254.169.254/")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			store := newFakeWebhookStorage(server.URL)
+			if err := NewWebhookDispatcher(store, testWebhookOptions(), discardLogger()).Sweep(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			d := store.delivery(1)
+			if d.Status != tt.wantStatus || d.Attempts != 1 {
+				t.Errorf("delivery status %s after %d attempts, want %s after 1", d.Status, d.Attempts, tt.wantStatus)
+			}
+			if d.LastStatus.Int32 != int32(tt.status) {
+				t.Errorf("last status = %d, want %d", d.LastStatus.Int32, tt.status)
+			}
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	d := NewWebhookDispatcher(nil, WebhookOptions{BackoffBase: time.Second, BackoffMax: 10 * time.Second}, discardLogger())
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{3, 8 * time.Second},
+		{4, 10 * time.Second},
+		{29, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookFailureSchedulesRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	opts := testWebhookOptions()
+	opts.BackoffBase = time.Minute
+	opts.BackoffMax = time.Hour
+	store := newFakeWebhookStorage(server.URL)
+	start := time.Now()
+	if err := NewWebhookDispatcher(store, opts, discardLogger()).Sweep(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	d := store.delivery(1)
+	if wait := d.NextAttemptAt.Sub(start); wait < time.Minute || wait > time.Minute+5*time.Second {
+		t.Errorf("next attempt in %v, want about a minute", wait)
+	}
+	if !d.LastError.Valid {
+		t.Error("failure reason was not recorded")
+	}
+}
+
+func TestWebhookDeadAfterMaxAttemptsAndRedeliver(t *testing.T) {
+	var hits atomic.Int64
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	opts := testWebhookOptions()
+	store := newFakeWebhookStorage(server.URL)
+	dispatcher := NewWebhookDispatcher(store, opts, discardLogger())
+	ctx := context.Background()
+
+	for range opts.MaxAttempts + 2 {
+		if err := dispatcher.Sweep(ctx); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * opts.BackoffMax)
+	}
+	d := store.delivery(1)
+	if d.Status != webhookStorage.StatusDead || d.Attempts != opts.MaxAttempts {
+		t.Fatalf("delivery status %s after %d attempts, want dead after %d", d.Status, d.Attempts, opts.MaxAttempts)
+	}
+	if n := hits.Load(); n != int64(opts.MaxAttempts) {
+		t.Fatalf("subscriber got %d requests, want %d", n, opts.MaxAttempts)
+	}
+
+	owner := &Principal{User: userStorage.User{ID: store.sub.UserID}}
+	webhooks := NewWebhookService(store, staticAuthenticator{owner}, discardLogger())
+	if err := webhooks.Redeliver(ctx, "token", store.sub.ID.String(), "1"); err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if d := store.delivery(1); d.Status != webhookStorage.StatusPending || d.Attempts != 0 {
+		t.Fatalf("after Redeliver: status %s, %d attempts", d.Status, d.Attempts)
+	}
+
+	healthy.Store(true)
+	if err := dispatcher.Sweep(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := store.delivery(1); d.Status != webhookStorage.StatusDelivered || d.Attempts != 1 {
+		t.Errorf("redelivered: status %s after %d attempts, want delivered after 1", d.Status, d.Attempts)
+	}
+}
+
+func TestWebhookRefusesPrivateAddress(t *testing.T) {
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	opts := testWebhookOptions()
+	opts.AllowPrivate = false
+	store := newFakeWebhookStorage(server.URL)
+	if err := NewWebhookDispatcher(store, opts, discardLogger()).Sweep(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if hits.Load() != 0 {
+		t.Error("request reached a loopback address")
+	}
+	if d := store.delivery(1); d.Status != webhookStorage.StatusPending || d.LastStatus.Valid {
+		t.Errorf("delivery status %s, last status %v, want a pending retry without a response", d.Status, d.LastStatus)
+	}
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"document-server/internal/api/models"
+	"document-server/internal/logger"
+	"document-server/internal/storage"
+	documentStorage "document-server/internal/storage/document"
+	webhookStorage "document-server/internal/storage/webhook"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hedhyw/semerr/pkg/v1/semerr"
+)
+
+type WebhookService struct {
+	webhookStorage WebhookStorage
+	authenticator  Authenticator
+	logger         *slog.Logger
+}
+
+func NewWebhookService(webhookStorage WebhookStorage, authenticator Authenticator, logger *slog.Logger) *WebhookService {
+	return &WebhookService{
+		webhookStorage: webhookStorage,
+		authenticator:  authenticator,
+		logger:         logger,
+	}
+}
+
+func (s *WebhookService) log(ctx context.Context) *slog.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+// Create возвращает сгенерированный секрет только один раз.
+func (s *WebhookService) Create(ctx context.Context, req models.WebhookCreateRequestDTO) (*models.WebhookCreateResponseDTO, error) {
+	principal, err := requireScope(ctx, s.authenticator, req.Token, ScopeDocsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, semerr.NewBadRequestError(errors.New("url must be an absolute http or https URL"))
+	}
+	if len(req.Events) == 0 {
+		return nil, semerr.NewBadRequestError(errors.New("at least one event is required"))
+	}
+	var events []string
+	for _, event := range req.Events {
+		if !slices.Contains(documentStorage.EventTypes, event) {
+			return nil, semerr.NewBadRequestError(errors.New("unknown event " + event))
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			s.log(ctx).Error("failed to generate webhook secret", slog.String("error", err.Error()))
+			return nil, semerr.NewInternalServerError(err)
+		}
+		secret = hex.EncodeToString(raw)
+	}
+
+	sub := webhookStorage.Subscription{
+		ID:        uuid.New(),
+		UserID:    principal.User.ID,
+		URL:       target.String(),
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+	if err := s.webhookStorage.Create(ctx, sub); err != nil {
+		s.log(ctx).Error("failed to create webhook", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("webhook created", slog.String("webhook_id", sub.ID.String()), slog.String("user", principal.User.Login))
+	return &models.WebhookCreateResponseDTO{
+		WebhookDTO: webhookToDTO(sub),
+		Secret:     secret,
+	}, nil
+}
+
+func (s *WebhookService) List(ctx context.Context, token string) ([]models.WebhookDTO, error) {
+	principal, err := requireScope(ctx, s.authenticator, token, ScopeDocsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := s.webhookStorage.ListByUserID(ctx, principal.User.ID)
+	if err != nil {
+		s.log(ctx).Error("failed to list webhooks", slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	result := make([]models.WebhookDTO, 0, len(subs))
+	for _, sub := range subs {
+		result = append(result, webhookToDTO(sub))
+	}
+	return result, nil
+}
+
+func (s *WebhookService) Delete(ctx context.Context, token, id string) error {
+	principal, err := requireScope(ctx, s.authenticator, token, ScopeDocsRead)
+	if err != nil {
+		return err
+	}
+
+	subID, err := uuid.Parse(id)
+	if err != nil {
+		return semerr.NewBadRequestError(errors.New("invalid webhook ID"))
+	}
+
+	if err := s.webhookStorage.Delete(ctx, subID, principal.User.ID); err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return semerr.NewNotFoundError(err)
+		}
+		s.log(ctx).Error("failed to delete webhook", slog.String("webhook_id", id), slog.String("error", err.Error()))
+		return semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("webhook deleted", slog.String("webhook_id", id), slog.String("user", principal.User.Login))
+	return nil
+}
+
+// Deliveries: пустой status - любые.
+func (s *WebhookService) Deliveries(ctx context.Context, token, id, status, limitStr string) ([]models.WebhookDeliveryDTO, error) {
+	sub, err := s.ownSubscription(ctx, token, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case "", webhookStorage.StatusPending, webhookStorage.StatusDelivered, webhookStorage.StatusDead:
+	default:
+		return nil, semerr.NewBadRequestError(errors.New("status must be pending, delivered or dead"))
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	deliveries, err := s.webhookStorage.ListDeliveries(ctx, sub.ID, status, limit)
+	if err != nil {
+		s.log(ctx).Error("failed to list webhook deliveries", slog.String("webhook_id", id), slog.String("error", err.Error()))
+		return nil, semerr.NewInternalServerError(err)
+	}
+
+	result := make([]models.WebhookDeliveryDTO, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		result = append(result, webhookDeliveryToDTO(delivery))
+	}
+	return result, nil
+}
+
+// Redeliver ставит доставку в очередь заново, в том числе мертвую.
+func (s *WebhookService) Redeliver(ctx context.Context, token, id, deliveryID string) error {
+	sub, err := s.ownSubscription(ctx, token, id)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := strconv.ParseInt(deliveryID, 10, 64)
+	if err != nil {
+		return semerr.NewBadRequestError(errors.New("invalid delivery ID"))
+	}
+
+	if err := s.webhookStorage.Redeliver(ctx, delivery, sub.ID); err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return semerr.NewNotFoundError(errors.New("delivery not found"))
+		}
+		s.log(ctx).Error("failed to redeliver webhook", slog.String("webhook_id", id), slog.String("error", err.Error()))
+		return semerr.NewInternalServerError(err)
+	}
+
+	s.log(ctx).Info("webhook delivery requeued", slog.String("webhook_id", id), slog.Int64("delivery_id", delivery))
+	return nil
+}
+
+// ownSubscription возвращает подписку владельца токена; чужая подписка - 404.
+func (s *WebhookService) ownSubscription(ctx context.Context, token, id string) (webhookStorage.Subscription, error) {
+	principal, err := requireScope(ctx, s.authenticator, token, ScopeDocsRead)
+	if err != nil {
+		return webhookStorage.Subscription{}, err
+	}
+
+	subID, err := uuid.Parse(id)
+	if err != nil {
+		return webhookStorage.Subscription{}, semerr.NewBadRequestError(errors.New("invalid webhook ID"))
+	}
+
+	sub, err := s.webhookStorage.Get(ctx, subID)
+	if err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return webhookStorage.Subscription{}, semerr.NewNotFoundError(err)
+		}
+		s.log(ctx).Error("failed to get webhook", slog.String("webhook_id", id), slog.String("error", err.Error()))
+		return webhookStorage.Subscription{}, semerr.NewInternalServerError(err)
+	}
+	if sub.UserID != principal.User.ID {
+		return webhookStorage.Subscription{}, semerr.NewNotFoundError(storage.ErrWebhookNotFound)
+	}
+	return sub, nil
+}
+
+func webhookToDTO(sub webhookStorage.Subscription) models.WebhookDTO {
+	return models.WebhookDTO{
+		ID:        sub.ID.String(),
+		URL:       sub.URL,
+		Events:    sub.Events,
+		CreatedAt: sub.CreatedAt,
+	}
+}
+
+func webhookDeliveryToDTO(delivery webhookStorage.Delivery) models.WebhookDeliveryDTO {
+	dto := models.WebhookDeliveryDTO{
+		ID:         delivery.ID,
+		EventID:    delivery.EventID,
+		Event:      delivery.EventType,
+		Document:   delivery.DocumentID.String(),
+		Status:     delivery.Status,
+		Attempts:   delivery.Attempts,
+		LastStatus: int(delivery.LastStatus.Int32),
+		LastError:  delivery.LastError.String,
+		CreatedAt:  delivery.CreatedAt,
+	}
+	if delivery.Status == webhookStorage.StatusPending {
+		dto.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.DeliveredAt.Valid {
+		dto.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return dto
+}
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type APIKey struct {
+	ID         uuid.UUID      `db:"id"`
+	UserID     uuid.UUID      `db:"user_id"`
+	Name       string         `db:"name"`
+	KeyPrefix  string         `db:"key_prefix"`
+	KeyHash    string         `db:"key_hash"`
+	Scopes     pq.StringArray `db:"scopes"`
+	AllowedIPs pq.StringArray `db:"allowed_ips"`
+	ExpiresAt  sql.NullTime   `db:"expires_at"`
+	LastUsedAt sql.NullTime   `db:"last_used_at"`
+	RevokedAt  sql.NullTime   `db:"revoked_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/storage"
+	"document-server/internal/tracing"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type APIKeyStorage struct {
+	db *sqlx.DB
+}
+
+func NewAPIKeyStorage(db *sqlx.DB) *APIKeyStorage {
+	return &APIKeyStorage{db: db}
+}
+
+func (s *APIKeyStorage) Create(ctx context.Context, key APIKey) (err error) {
+	ctx, span := tracing.StartDB(ctx, "APIKeyStorage.Create")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO api_keys (id, user_id, name, key_prefix, key_hash, scopes, allowed_ips, expires_at)
+		VALUES (:id, :user_id, :name, :key_prefix, :key_hash, :scopes, :allowed_ips, :expires_at)
+	`
+	_, err = s.db.NamedExecContext(ctx, query, key)
+	return err
+}
+
+func (s *APIKeyStorage) GetByHash(ctx context.Context, hash string) (_ APIKey, err error) {
+	ctx, span := tracing.StartDB(ctx, "APIKeyStorage.GetByHash")
+	defer tracing.End(span, &err)
+
+	var key APIKey
+	query := "SELECT * FROM api_keys WHERE key_hash=$1"
+	if err := s.db.GetContext(ctx, &key, query, hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIKey{}, storage.ErrAPIKeyNotFound
+		}
+		return APIKey{}, err
+	}
+	return key, nil
+}
+
+func (s *APIKeyStorage) ListByUserID(ctx context.Context, userID uuid.UUID) (_ []APIKey, err error) {
+	ctx, span := tracing.StartDB(ctx, "APIKeyStorage.ListByUserID")
+	defer tracing.End(span, &err)
+
+	var keys []APIKey
+	query := "SELECT * FROM api_keys WHERE user_id=$1 ORDER BY created_at DESC"
+	if err := s.db.SelectContext(ctx, &keys, query, userID); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *APIKeyStorage) Revoke(ctx context.Context, id, userID uuid.UUID) (err error) {
+	ctx, span := tracing.StartDB(ctx, "APIKeyStorage.Revoke")
+	defer tracing.End(span, &err)
+
+	query := "UPDATE api_keys SET revoked_at = NOW() WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL"
+	res, err := s.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (s *APIKeyStorage) TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) (err error) {
+	ctx, span := tracing.StartDB(ctx, "APIKeyStorage.TouchLastUsed")
+	defer tracing.End(span, &err)
+
+	query := "UPDATE api_keys SET last_used_at=$2 WHERE id=$1"
+	_, err = s.db.ExecContext(ctx, query, id, usedAt)
+	return err
+}
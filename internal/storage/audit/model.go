@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Event: Actor - логин или имя системного процесса.
+type Event struct {
+	ID         int64          `db:"id"`
+	OccurredAt time.Time      `db:"occurred_at"`
+	Action     string         `db:"action"`
+	Outcome    string         `db:"outcome"`
+	Actor      sql.NullString `db:"actor"`
+	Target     sql.NullString `db:"target"`
+	IP         sql.NullString `db:"ip"`
+	UserAgent  sql.NullString `db:"user_agent"`
+	RequestID  sql.NullString `db:"request_id"`
+	Detail     sql.NullString `db:"detail"`
+}
+
+// Filter отбирает события; пустые поля не ограничивают выборку. From включается, To - нет.
+type Filter struct {
+	Actor  string
+	Target string
+	Action string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
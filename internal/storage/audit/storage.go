@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"document-server/internal/metrics"
+	"document-server/internal/tracing"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditStorage: изменение audit_events запрещено триггером.
+type AuditStorage struct {
+	db *sqlx.DB
+}
+
+func NewAuditStorage(db *sqlx.DB) *AuditStorage {
+	return &AuditStorage{db: db}
+}
+
+func (s *AuditStorage) Append(ctx context.Context, event Event) (err error) {
+	ctx, span := tracing.StartDB(ctx, "AuditStorage.Append")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("audit", "append", time.Now(), &err)
+
+	query := `
+		INSERT INTO audit_events (action, outcome, actor, target, ip, user_agent, request_id, detail)
+		VALUES (:action, :outcome, :actor, :target, :ip, :user_agent, :request_id, :detail)
+	`
+	_, err = s.db.NamedExecContext(ctx, query, event)
+	return err
+}
+
+// List возвращает события по фильтру, начиная с последних.
+func (s *AuditStorage) List(ctx context.Context, filter Filter) (_ []Event, err error) {
+	ctx, span := tracing.StartDB(ctx, "AuditStorage.List")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("audit", "list", time.Now(), &err)
+
+	where, args := filter.where()
+	query := "SELECT * FROM audit_events" + where + " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+
+	var events []Event
+	if err := s.db.SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Export передает в fn события по фильтру в порядке записи, не загружая их в память целиком.
+func (s *AuditStorage) Export(ctx context.Context, filter Filter, fn func(Event) error) (err error) {
+	ctx, span := tracing.StartDB(ctx, "AuditStorage.Export")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("audit", "export", time.Now(), &err)
+
+	where, args := filter.where()
+	rows, err := s.db.QueryxContext(ctx, "SELECT * FROM audit_events"+where+" ORDER BY id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event Event
+		if err := rows.StructScan(&event); err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (f Filter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, cond+" $"+strconv.Itoa(len(args)))
+	}
+
+	if f.Actor != "" {
+		add("actor =", f.Actor)
+	}
+	if f.Target != "" {
+		add("target =", f.Target)
+	}
+	if f.Action != "" {
+		add("action =", f.Action)
+	}
+	if !f.From.IsZero() {
+		add("occurred_at >=", f.From)
+	}
+	if !f.To.IsZero() {
+		add("occurred_at <", f.To)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
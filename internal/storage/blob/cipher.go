@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Файл: заголовок "DSE1" | chunkSize | префикс nonce, затем блоки AES-GCM; флаг последнего блока в AAD.
+const (
+	chunkSize  = 64 << 10
+	headerSize = 16
+	tagSize    = 16
+)
+
+var cipherMagic = []byte("DSE1")
+
+var errCorrupted = errors.New("encrypted file is corrupted")
+
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	buf    []byte
+	index  uint32
+}
+
+func newEncryptWriter(w io.Writer, dataKey []byte) (*encryptWriter, error) {
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, cipherMagic)
+	binary.BigEndian.PutUint32(header[4:8], chunkSize)
+	if _, err := rand.Read(header[8:]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{w: w, aead: aead, header: header, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// последний блок остается в буфере до Close
+		if len(e.buf) == chunkSize {
+			if err := e.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close дописывает последний блок; сам w не закрывается.
+func (e *encryptWriter) Close() error {
+	return e.flush(true)
+}
+
+func (e *encryptWriter) flush(last bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.header, e.index), e.buf, chunkAAD(e.header, last))
+	if _, err := e.w.Write(sealed); err != nil {
+		return err
+	}
+	e.index++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// decryptReader расшифровывает файл по блокам и поддерживает Seek по открытому тексту.
+type decryptReader struct {
+	r      io.ReadSeeker
+	aead   cipher.AEAD
+	header []byte
+	chunks int64
+	size   int64
+
+	pos     int64
+	current int64
+	plain   []byte
+}
+
+func newDecryptReader(r io.ReadSeeker, fileSize int64, dataKey []byte) (*decryptReader, error) {
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorrupted, err)
+	}
+	if !bytes.Equal(header[:4], cipherMagic) || binary.BigEndian.Uint32(header[4:8]) != chunkSize {
+		return nil, errCorrupted
+	}
+
+	body := fileSize - headerSize
+	sealedChunk := int64(chunkSize + tagSize)
+	chunks := body / sealedChunk
+	rem := body % sealedChunk
+	size := chunks * chunkSize
+	if rem > 0 {
+		if rem < tagSize {
+			return nil, errCorrupted
+		}
+		chunks++
+		size += rem - tagSize
+	}
+	if chunks == 0 {
+		return nil, errCorrupted
+	}
+
+	return &decryptReader{r: r, aead: aead, header: header, chunks: chunks, size: size, current: -1}, nil
+}
+
+// Size возвращает длину открытого текста.
+func (d *decryptReader) Size() int64 {
+	return d.size
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	if d.pos >= d.size {
+		return 0, io.EOF
+	}
+	index := d.pos / chunkSize
+	if index != d.current {
+		if err := d.load(index); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain[d.pos-index*chunkSize:])
+	d.pos += int64(n)
+	return n, nil
+}
+
+func (d *decryptReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += d.pos
+	case io.SeekEnd:
+		offset += d.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	d.pos = offset
+	return offset, nil
+}
+
+func (d *decryptReader) load(index int64) error {
+	if _, err := d.r.Seek(headerSize+index*(chunkSize+tagSize), io.SeekStart); err != nil {
+		return err
+	}
+	sealed := make([]byte, chunkSize+tagSize)
+	n, err := io.ReadFull(d.r, sealed)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+
+	last := index == d.chunks-1
+	plain, err := d.aead.Open(sealed[:0], chunkNonce(d.header, uint32(index)), sealed[:n], chunkAAD(d.header, last))
+	if err != nil {
+		return errCorrupted
+	}
+	d.plain = plain
+	d.current = index
+	return nil
+}
+
+func chunkNonce(header []byte, index uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, header[8:16])
+	binary.BigEndian.PutUint32(nonce[8:], index)
+	return nonce
+}
+
+func chunkAAD(header []byte, last bool) []byte {
+	aad := make([]byte, headerSize+1)
+	copy(aad, header)
+	if last {
+		aad[headerSize] = 1
+	}
+	return aad
+}
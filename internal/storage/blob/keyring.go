@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"document-server/internal/config"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const keySize = 32
+
+var ErrUnknownKey = errors.New("unknown master key")
+
+// Keyring хранит мастер-ключи по ID; новые ключи документов оборачиваются активным.
+type Keyring struct {
+	activeID string
+	keys     map[string][]byte
+}
+
+// LoadKeyring возвращает nil, если шифрование выключено.
+func LoadKeyring(cfg config.EncryptionConfig) (*Keyring, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	encoded := map[string]string{}
+	if cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &encoded); err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.KeyFile, err)
+		}
+	}
+	if cfg.MasterKey != "" {
+		encoded[cfg.KeyID] = cfg.MasterKey
+	}
+
+	ring := &Keyring{activeID: cfg.KeyID, keys: make(map[string][]byte, len(encoded))}
+	for id, value := range encoded {
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("master key %q must be %d base64-encoded bytes", id, keySize)
+		}
+		ring.keys[id] = key
+	}
+	if _, ok := ring.keys[cfg.KeyID]; !ok {
+		return nil, fmt.Errorf("active master key %q is not configured", cfg.KeyID)
+	}
+	return ring, nil
+}
+
+// ActiveID возвращает ID ключа, которым оборачиваются новые ключи.
+func (k *Keyring) ActiveID() string {
+	return k.activeID
+}
+
+// wrap шифрует ключ документа активным мастер-ключом: nonce || AES-GCM(dataKey).
+func (k *Keyring) wrap(dataKey []byte) (string, []byte, error) {
+	aead, err := newGCM(k.keys[k.activeID])
+	if err != nil {
+		return "", nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return k.activeID, aead.Seal(nonce, nonce, dataKey, []byte(k.activeID)), nil
+}
+
+func (k *Keyring) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	aead, err := newGCM(master)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is truncated")
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, []byte(keyID))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
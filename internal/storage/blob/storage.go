@@ -0,0 +1,366 @@
+package storage
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"document-server/internal/metrics"
+	"document-server/internal/tracing"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// tempPattern - незавершенные загрузки; после падения их убирает сверка.
+const tempPattern = ".upload-*"
+
+const EncodingGzip = "gzip"
+
+type Options struct {
+	MD5              bool
+	Keyring          *Keyring // nil - без шифрования
+	CompressionLevel int
+}
+
+// Ref: пустой KeyID - файл не зашифрован, пустой Encoding - не сжат.
+type Ref struct {
+	Path       string
+	KeyID      string
+	WrappedKey []byte
+	Encoding   string
+}
+
+type FileStore struct {
+	dir  string
+	opts Options
+
+	// подменяются в тестах
+	createTemp func(dir, pattern string) (*os.File, error)
+	fsync      func(f *os.File) error
+	chmod      func(name string, mode os.FileMode) error
+	rename     func(oldpath, newpath string) error
+}
+
+func NewFileStore(dir string, opts Options) *FileStore {
+	return &FileStore{
+		dir:        dir,
+		opts:       opts,
+		createTemp: os.CreateTemp,
+		fsync:      (*os.File).Sync,
+		chmod:      os.Chmod,
+		rename:     os.Rename,
+	}
+}
+
+// Stage пишет r во временный файл; под именем name он появится только после Commit.
+func (s *FileStore) Stage(ctx context.Context, name string, r io.Reader, compress bool) (_ *StagedFile, err error) {
+	_, span := tracing.Start(ctx, "FileStore.Stage")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("blob", "stage", time.Now(), &err)
+
+	tmp, err := s.createTemp(s.dir, tempPattern)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	staged := &StagedFile{
+		dir:    s.dir,
+		tmp:    tmp.Name(),
+		path:   filepath.Join(s.dir, name),
+		rename: s.rename,
+	}
+
+	var sink io.Writer = tmp
+	var encryptor *encryptWriter
+	if s.opts.Keyring != nil {
+		dataKey := make([]byte, keySize)
+		if _, err := rand.Read(dataKey); err != nil {
+			return nil, err
+		}
+		if staged.keyID, staged.wrappedKey, err = s.opts.Keyring.wrap(dataKey); err != nil {
+			return nil, err
+		}
+		if encryptor, err = newEncryptWriter(tmp, dataKey); err != nil {
+			return nil, err
+		}
+		sink = encryptor
+	}
+
+	var compressor *gzip.Writer
+	if compress {
+		if compressor, err = gzip.NewWriterLevel(sink, s.opts.CompressionLevel); err != nil {
+			return nil, err
+		}
+		staged.encoding = EncodingGzip
+		sink = compressor
+	}
+
+	sha := sha256.New()
+	writers := []io.Writer{sink, sha}
+	var sum hash.Hash
+	if s.opts.MD5 {
+		sum = md5.New()
+		writers = append(writers, sum)
+	}
+
+	staged.size, err = io.Copy(io.MultiWriter(writers...), r)
+	if err != nil {
+		return nil, err
+	}
+	if compressor != nil {
+		if err := compressor.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if encryptor != nil {
+		if err := encryptor.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.fsync(tmp); err != nil {
+		return nil, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return nil, err
+	}
+	staged.storedSize = info.Size()
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := s.chmod(tmp.Name(), 0644); err != nil {
+		return nil, err
+	}
+
+	staged.sha256 = hex.EncodeToString(sha.Sum(nil))
+	if sum != nil {
+		staged.md5 = hex.EncodeToString(sum.Sum(nil))
+	}
+	return staged, nil
+}
+
+func (s *FileStore) Open(ctx context.Context, ref Ref) (_ io.ReadCloser, err error) {
+	_, span := tracing.Start(ctx, "FileStore.Open")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("blob", "open", time.Now(), &err)
+
+	stored, err := s.openStored(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ref.Encoding {
+	case "":
+		return stored, nil
+	case EncodingGzip:
+		zr, err := gzip.NewReader(stored)
+		if err != nil {
+			stored.Close()
+			return nil, err
+		}
+		return &decodeReader{Reader: zr, stored: stored}, nil
+	default:
+		stored.Close()
+		return nil, fmt.Errorf("unsupported content encoding %q", ref.Encoding)
+	}
+}
+
+// OpenStored расшифровывает содержимое, но не распаковывает его.
+func (s *FileStore) OpenStored(ctx context.Context, ref Ref) (_ io.ReadSeekCloser, err error) {
+	_, span := tracing.Start(ctx, "FileStore.OpenStored")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("blob", "open", time.Now(), &err)
+
+	return s.openStored(ref)
+}
+
+func (s *FileStore) ReadAll(ctx context.Context, ref Ref) (_ []byte, err error) {
+	f, err := s.Open(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func (s *FileStore) Size(ctx context.Context, path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *FileStore) Remove(ctx context.Context, path string) (err error) {
+	_, span := tracing.Start(ctx, "FileStore.Remove")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("blob", "remove", time.Now(), &err)
+
+	return os.Remove(path)
+}
+
+// Rewrap оборачивает ключ документа активным мастер-ключом, не перешифровывая файл.
+func (s *FileStore) Rewrap(ref Ref) (keyID string, wrappedKey []byte, changed bool, err error) {
+	ring := s.opts.Keyring
+	if ring == nil || ref.KeyID == "" || ref.KeyID == ring.ActiveID() {
+		return ref.KeyID, ref.WrappedKey, false, nil
+	}
+
+	dataKey, err := ring.unwrap(ref.KeyID, ref.WrappedKey)
+	if err != nil {
+		return "", nil, false, err
+	}
+	keyID, wrappedKey, err = ring.wrap(dataKey)
+	if err != nil {
+		return "", nil, false, err
+	}
+	return keyID, wrappedKey, true, nil
+}
+
+func (s *FileStore) openStored(ref Ref) (io.ReadSeekCloser, error) {
+	f, err := os.Open(ref.Path)
+	if err != nil {
+		return nil, err
+	}
+	if ref.KeyID == "" {
+		return f, nil
+	}
+
+	reader, err := s.decrypt(f, ref)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+func (s *FileStore) decrypt(f *os.File, ref Ref) (*decryptFile, error) {
+	if s.opts.Keyring == nil {
+		return nil, errors.New("file is encrypted but encryption is not configured")
+	}
+	dataKey, err := s.opts.Keyring.unwrap(ref.KeyID, ref.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newDecryptReader(f, info.Size(), dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptFile{decryptReader: reader, file: f}, nil
+}
+
+type decryptFile struct {
+	*decryptReader
+	file *os.File
+}
+
+func (d *decryptFile) Close() error {
+	return d.file.Close()
+}
+
+type decodeReader struct {
+	io.Reader
+	stored io.Closer
+}
+
+func (d *decodeReader) Close() error {
+	return d.stored.Close()
+}
+
+type StagedFile struct {
+	dir        string
+	tmp        string
+	path       string
+	size       int64
+	sha256     string
+	md5        string
+	storedSize int64
+	encoding   string
+	keyID      string
+	wrappedKey []byte
+	committed  bool
+	rename     func(oldpath, newpath string) error
+}
+
+func (f *StagedFile) Path() string {
+	return f.path
+}
+
+func (f *StagedFile) Size() int64 {
+	return f.size
+}
+
+func (f *StagedFile) StoredSize() int64 {
+	return f.storedSize
+}
+
+func (f *StagedFile) Encoding() string {
+	return f.encoding
+}
+
+func (f *StagedFile) SHA256() string {
+	return f.sha256
+}
+
+func (f *StagedFile) MD5() string {
+	return f.md5
+}
+
+func (f *StagedFile) KeyID() string {
+	return f.keyID
+}
+
+func (f *StagedFile) WrappedKey() []byte {
+	return f.wrappedKey
+}
+
+func (f *StagedFile) Commit() error {
+	if err := f.rename(f.tmp, f.path); err != nil {
+		return err
+	}
+	f.committed = true
+	return syncDir(f.dir)
+}
+
+// Abort удаляет временный файл, а после Commit - итоговый.
+func (f *StagedFile) Abort() error {
+	path := f.tmp
+	if f.committed {
+		path = f.path
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
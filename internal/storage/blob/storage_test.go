@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+var errInjected = errors.New("injected failure")
+
+// failingReader отдает часть данных и затем обрывает поток, как отвалившийся клиент.
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errInjected
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestStageFaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		inject func(s *FileStore)
+		body   func() io.Reader
+		// commit - сбой происходит в Commit, а не в Stage.
+		commit bool
+	}{
+		{
+			name: "create temp",
+			inject: func(s *FileStore) {
+				s.createTemp = func(string, string) (*os.File, error) { return nil, errInjected }
+			},
+		},
+		{
+			name: "mid-stream read",
+			body: func() io.Reader { return &failingReader{data: bytes.Repeat([]byte("a"), 100)} },
+		},
+		{
+			name: "fsync",
+			inject: func(s *FileStore) {
+				s.fsync = func(*os.File) error { return errInjected }
+			},
+		},
+		{
+			name: "chmod",
+			inject: func(s *FileStore) {
+				s.chmod = func(string, os.FileMode) error { return errInjected }
+			},
+		},
+		{
+			name: "rename",
+			inject: func(s *FileStore) {
+				s.rename = func(string, string) error { return errInjected }
+			},
+			commit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, compress := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/compress=%v", tt.name, compress), func(t *testing.T) {
+				dir := t.TempDir()
+				store := NewFileStore(dir, Options{MD5: true})
+				if tt.inject != nil {
+					tt.inject(store)
+				}
+				var body io.Reader = bytes.NewReader([]byte("hello"))
+				if tt.body != nil {
+					body = tt.body()
+				}
+
+				staged, err := store.Stage(context.Background(), "doc", body, compress)
+				if tt.commit {
+					if err != nil {
+						t.Fatalf("Stage: %v", err)
+					}
+					err = staged.Commit()
+					if abortErr := staged.Abort(); abortErr != nil {
+						t.Fatalf("Abort: %v", abortErr)
+					}
+				}
+				if !errors.Is(err, errInjected) {
+					t.Fatalf("got error %v, want injected failure", err)
+				}
+				assertEmptyDir(t, dir)
+			})
+		}
+	}
+}
+
+func TestAbortAfterCommitRemovesFile(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, Options{})
+
+	staged, err := store.Stage(context.Background(), "doc", bytes.NewReader([]byte("hello")), false)
+	if err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+	if err := staged.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if _, err := os.Stat(staged.Path()); err != nil {
+		t.Fatalf("committed file: %v", err)
+	}
+
+	// Так откатывается загрузка, у которой после переименования не прошел COMMIT.
+	if err := staged.Abort(); err != nil {
+		t.Fatalf("Abort: %v", err)
+	}
+	assertEmptyDir(t, dir)
+}
+
+func TestOpenStoredSeeks(t *testing.T) {
+	data := make([]byte, 2*chunkSize+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	ring := &Keyring{activeID: "k1", keys: map[string][]byte{"k1": bytes.Repeat([]byte{7}, keySize)}}
+
+	for _, ring := range []*Keyring{nil, ring} {
+		t.Run(fmt.Sprintf("encrypted=%v", ring != nil), func(t *testing.T) {
+			store := NewFileStore(t.TempDir(), Options{Keyring: ring})
+			staged, err := store.Stage(context.Background(), "doc", bytes.NewReader(data), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := staged.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := store.OpenStored(context.Background(), Ref{Path: staged.Path(), KeyID: staged.KeyID(), WrappedKey: staged.WrappedKey()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			// Так http.ServeContent узнает размер и отдает диапазон через границу блока.
+			if size, err := f.Seek(0, io.SeekEnd); err != nil || size != int64(len(data)) {
+				t.Fatalf("Seek(0, end) = %d, %v, want %d", size, err, len(data))
+			}
+			offset := int64(chunkSize - 10)
+			if _, err := f.Seek(offset, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			got := make([]byte, 20)
+			if _, err := io.ReadFull(f, got); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data[offset:offset+20]) {
+				t.Error("range read returned wrong bytes")
+			}
+		})
+	}
+}
+
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		t.Errorf("file left behind: %s", e.Name())
+	}
+}
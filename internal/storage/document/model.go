@@ -8,14 +8,89 @@ import (
 	"github.com/lib/pq"
 )
 
+// Document: StoredSize - размер на диске, у JSON документов пуст; DeletedAt - документ в корзине.
 type Document struct {
-	ID        uuid.UUID      `db:"id"`
-	Name      string         `db:"name"`
-	MimeType  string         `db:"mime_type"`
-	IsPublic  bool           `db:"public"`
-	IsFile    bool           `db:"file"`
-	FilePath  sql.NullString `db:"content_path"`
-	JSONData  sql.NullString `db:"json_content"`
-	CreatedAt time.Time      `db:"created_at"`
-	GrantedTo pq.StringArray `db:"granted_to"`
+	ID              uuid.UUID      `db:"id"`
+	Name            string         `db:"name"`
+	MimeType        string         `db:"mime_type"`
+	IsPublic        bool           `db:"public"`
+	IsFile          bool           `db:"file"`
+	FilePath        sql.NullString `db:"content_path"`
+	JSONData        sql.NullString `db:"json_content"`
+	CreatedAt       time.Time      `db:"created_at"`
+	GrantedTo       pq.StringArray `db:"granted_to"`
+	OriginalName    sql.NullString `db:"original_name"`
+	SHA256          sql.NullString `db:"sha256"`
+	MD5             sql.NullString `db:"md5"`
+	KeyID           sql.NullString `db:"encryption_key_id"`
+	WrappedKey      []byte         `db:"wrapped_key"`
+	Size            sql.NullInt64  `db:"size"`
+	StoredSize      sql.NullInt64  `db:"stored_size"`
+	ContentEncoding sql.NullString `db:"content_encoding"`
+	OwnerID         uuid.NullUUID  `db:"owner_id"`
+	ExpiresAt       sql.NullTime   `db:"expires_at"`
+	LegalHold       bool           `db:"legal_hold"`
+	DeletedAt       sql.NullTime   `db:"deleted_at"`
+}
+
+// Типы событий document_events; событие пишется в той же транзакции, что и изменение.
+const (
+	EventCreated  = "document.created"
+	EventUpdated  = "document.updated"
+	EventDeleted  = "document.deleted"
+	EventRestored = "document.restored"
+	EventGranted  = "document.granted"
+)
+
+// ChangesChannel - канал NOTIFY с ID измененных документов.
+const ChangesChannel = "document_changes"
+
+var EventTypes = []string{EventCreated, EventUpdated, EventDeleted, EventRestored, EventGranted}
+
+// Expired сообщает, что срок хранения истек; документ под удержанием не истекает.
+func (d *Document) Expired(now time.Time) bool {
+	return !d.LegalHold && d.ExpiresAt.Valid && !d.ExpiresAt.Time.After(now)
+}
+
+// ChargedBytes - размер на диске, а для JSON документов - исходный.
+func (d *Document) ChargedBytes() int64 {
+	if d.StoredSize.Valid {
+		return d.StoredSize.Int64
+	}
+	return d.Size.Int64
+}
+
+// Quota - ограничения владельца документов; 0 - без ограничения.
+type Quota struct {
+	MaxBytes     int64
+	MaxDocuments int64
+}
+
+// Limits - квота владельца и, если он состоит в группе, общая квота группы.
+type Limits struct {
+	User       Quota
+	Group      string
+	GroupQuota Quota
+}
+
+func (q Quota) Allows(usage Usage) bool {
+	return (q.MaxBytes == 0 || usage.Bytes <= q.MaxBytes) &&
+		(q.MaxDocuments == 0 || usage.Documents <= q.MaxDocuments)
+}
+
+type Usage struct {
+	Bytes     int64 `db:"bytes"`
+	Documents int64 `db:"documents"`
+}
+
+type ContentRef struct {
+	ID         uuid.UUID      `db:"id"`
+	Name       string         `db:"name"`
+	Path       string         `db:"content_path"`
+	SHA256     sql.NullString `db:"sha256"`
+	MD5        sql.NullString `db:"md5"`
+	KeyID      sql.NullString `db:"encryption_key_id"`
+	WrappedKey []byte         `db:"wrapped_key"`
+	Encoding   sql.NullString `db:"content_encoding"`
+	Size       sql.NullInt64  `db:"size"`
 }
@@ -3,8 +3,11 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"document-server/internal/metrics"
 	"document-server/internal/storage"
+	"document-server/internal/tracing"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -19,16 +22,22 @@ func NewDocumentStorage(db *sqlx.DB) *DocumentStorage {
 	return &DocumentStorage{db: db}
 }
 
-func (s *DocumentStorage) Create(ctx context.Context, doc Document) error {
-	tx, err := s.db.Beginx()
+// Create откатывает вставку, если beforeCommit вернул ошибку или превышена квота владельца или группы.
+func (s *DocumentStorage) Create(ctx context.Context, doc Document, limits Limits, beforeCommit func() error) (err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.Create")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "create", time.Now(), &err)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
 	docQuery := `
-		INSERT INTO documents (id, name, mime_type, public, file, content_path, json_content, granted_to)
-		VALUES (:id, :name, :mime_type, :public, :file, :content_path, :json_content, :granted_to)
+		INSERT INTO documents (id, name, mime_type, public, file, content_path, json_content, granted_to, original_name, sha256, md5, encryption_key_id, wrapped_key, size, stored_size, content_encoding, owner_id, expires_at, legal_hold)
+		VALUES (:id, :name, :mime_type, :public, :file, :content_path, :json_content, :granted_to, :original_name, :sha256, :md5, :encryption_key_id, :wrapped_key, :size, :stored_size, :content_encoding, :owner_id, :expires_at, :legal_hold)
 	`
 
 	_, err = tx.NamedExecContext(ctx, docQuery, doc)
@@ -36,10 +45,59 @@ func (s *DocumentStorage) Create(ctx context.Context, doc Document) error {
 		return err
 	}
 
+	eventQuery := `INSERT INTO document_events (event_type, document_id, name, granted_to) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, eventQuery, EventCreated, doc.ID, doc.Name, doc.GrantedTo); err != nil {
+		return err
+	}
+
+	if doc.OwnerID.Valid {
+		// блокировка user_usage упорядочивает параллельные загрузки
+		usageQuery := `
+			INSERT INTO user_usage (user_id, bytes, documents) VALUES ($1, $2, 1)
+			ON CONFLICT (user_id) DO UPDATE
+			SET bytes = user_usage.bytes + EXCLUDED.bytes, documents = user_usage.documents + 1
+			RETURNING bytes, documents
+		`
+		var usage Usage
+		if err := tx.GetContext(ctx, &usage, usageQuery, doc.OwnerID.UUID, doc.ChargedBytes()); err != nil {
+			return err
+		}
+		if !limits.User.Allows(usage) {
+			return storage.ErrQuotaExceeded
+		}
+
+		if limits.Group != "" && limits.GroupQuota != (Quota{}) {
+			// группа всегда блокируется после пользователя, без взаимных блокировок
+			lockQuery := `SELECT name FROM user_groups WHERE name = $1 FOR UPDATE`
+			var name string
+			if err := tx.GetContext(ctx, &name, lockQuery, limits.Group); err != nil {
+				return err
+			}
+			var groupUsage Usage
+			if err := tx.GetContext(ctx, &groupUsage, groupUsageQuery, limits.Group); err != nil {
+				return err
+			}
+			if !limits.GroupQuota.Allows(groupUsage) {
+				return storage.ErrGroupQuotaExceeded
+			}
+		}
+	}
+
+	if beforeCommit != nil {
+		if err := beforeCommit(); err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit()
 }
 
-func (s *DocumentStorage) GetByID(ctx context.Context, id string) (*Document, error) {
+func (s *DocumentStorage) GetByID(ctx context.Context, id string) (_ *Document, err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.GetByID")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "get_by_id", time.Now(), &err)
+
 	var doc Document
 	query := "SELECT * FROM documents WHERE id=$1"
 	if err := s.db.GetContext(ctx, &doc, query, id); err != nil {
@@ -51,13 +109,18 @@ func (s *DocumentStorage) GetByID(ctx context.Context, id string) (*Document, er
 	return &doc, nil
 }
 
-func (s *DocumentStorage) ListDocumentIDs(ctx context.Context, currentLogin string, filterLogin string, key string, value string, limit int) ([]string, error) {
+func (s *DocumentStorage) ListDocumentIDs(ctx context.Context, currentLogin string, filterLogin string, key string, value string, limit int) (_ []string, err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.ListDocumentIDs")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "list_ids", time.Now(), &err)
+
 	searchLogin := currentLogin
 	if filterLogin != "" {
 		searchLogin = filterLogin
 	}
 
-	query := `SELECT id FROM documents WHERE $1 = ANY(granted_to)`
+	query := `SELECT id FROM documents WHERE $1 = ANY(granted_to) AND deleted_at IS NULL AND (expires_at IS NULL OR legal_hold OR expires_at > NOW())`
 	args := []interface{}{searchLogin}
 
 	if key != "" && value != "" {
@@ -85,7 +148,12 @@ func (s *DocumentStorage) ListDocumentIDs(ctx context.Context, currentLogin stri
 	return ids, nil
 }
 
-func (s *DocumentStorage) GetDocumentsByIDs(ctx context.Context, ids []string) ([]Document, error) {
+func (s *DocumentStorage) GetDocumentsByIDs(ctx context.Context, ids []string) (_ []Document, err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.GetDocumentsByIDs")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "get_by_ids", time.Now(), &err)
+
 	query := `SELECT * FROM documents WHERE id = ANY($1)`
 	var docs []Document
 	if err := s.db.SelectContext(ctx, &docs, query, pq.Array(ids)); err != nil {
@@ -94,8 +162,255 @@ func (s *DocumentStorage) GetDocumentsByIDs(ctx context.Context, ids []string) (
 	return docs, nil
 }
 
-func (s *DocumentStorage) DeleteDocumentByID(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM documents WHERE id = $1`
-	_, err := s.db.ExecContext(ctx, query, id)
+// DeleteDocumentByID не удаляет документ под удержанием.
+func (s *DocumentStorage) DeleteDocumentByID(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.DeleteDocumentByID")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "delete", time.Now(), &err)
+
+	// для документа из корзины событие уже записано в MoveToTrash
+	query := `
+		WITH deleted AS (
+			DELETE FROM documents WHERE id = $1 AND NOT legal_hold
+			RETURNING id, name, granted_to, deleted_at, owner_id, COALESCE(stored_size, size, 0) AS bytes
+		), usage AS (
+			UPDATE user_usage SET bytes = user_usage.bytes - deleted.bytes, documents = user_usage.documents - 1
+			FROM deleted WHERE user_usage.user_id = deleted.owner_id
+		), event AS (
+			INSERT INTO document_events (event_type, document_id, name, granted_to)
+			SELECT '` + EventDeleted + `', id, name, granted_to FROM deleted WHERE deleted_at IS NULL
+		)
+		SELECT COUNT(*) FROM deleted
+	`
+	var deleted int
+	if err := s.db.GetContext(ctx, &deleted, query, id); err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return storage.ErrDocumentNotFound
+	}
+	return nil
+}
+
+// List возвращает документы без учета прав доступа; login фильтрует по granted_to.
+func (s *DocumentStorage) List(ctx context.Context, login string, limit int) (_ []Document, err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.List")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "list", time.Now(), &err)
+
+	query := `SELECT * FROM documents WHERE ($1 = '' OR $1 = ANY(granted_to)) ORDER BY created_at DESC`
+	args := []interface{}{login}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	var docs []Document
+	if err := s.db.SelectContext(ctx, &docs, query, args...); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (s *DocumentStorage) ListContentRefs(ctx context.Context) (_ []ContentRef, err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.ListContentRefs")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "list_content_refs", time.Now(), &err)
+
+	var refs []ContentRef
+	query := `SELECT id, name, content_path, sha256, md5, encryption_key_id, wrapped_key, content_encoding, size FROM documents WHERE content_path IS NOT NULL`
+	if err := s.db.SelectContext(ctx, &refs, query); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func (s *DocumentStorage) SetChecksums(ctx context.Context, id uuid.UUID, sha256, md5 sql.NullString) (err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.SetChecksums")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "set_checksums", time.Now(), &err)
+
+	query := `UPDATE documents SET sha256 = $2, md5 = $3 WHERE id = $1`
+	_, err = s.db.ExecContext(ctx, query, id, sha256, md5)
 	return err
 }
+
+func (s *DocumentStorage) SetWrappedKey(ctx context.Context, id uuid.UUID, keyID string, wrappedKey []byte) (err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.SetWrappedKey")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "set_wrapped_key", time.Now(), &err)
+
+	query := `UPDATE documents SET encryption_key_id = $2, wrapped_key = $3 WHERE id = $1`
+	_, err = s.db.ExecContext(ctx, query, id, keyID, wrappedKey)
+	return err
+}
+
+// SetSizes поправляет использование владельца на разницу с прежним размером.
+func (s *DocumentStorage) SetSizes(ctx context.Context, id uuid.UUID, size, storedSize int64) (err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.SetSizes")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "set_sizes", time.Now(), &err)
+
+	query := `
+		WITH old AS (
+			SELECT owner_id, COALESCE(stored_size, size, 0) AS bytes FROM documents WHERE id = $1 FOR UPDATE
+		), updated AS (
+			UPDATE documents SET size = $2, stored_size = $3 WHERE id = $1
+		)
+		UPDATE user_usage SET bytes = user_usage.bytes - old.bytes + $3
+		FROM old WHERE user_usage.user_id = old.owner_id
+	`
+	_, err = s.db.ExecContext(ctx, query, id, size, storedSize)
+	return err
+}
+
+// groupUsageQuery: счетчика у группы нет, смена группы не требует пересчета.
+const groupUsageQuery = `
+	SELECT COALESCE(SUM(user_usage.bytes), 0) AS bytes, COALESCE(SUM(user_usage.documents), 0) AS documents
+	FROM user_usage JOIN users ON users.id = user_usage.user_id
+	WHERE users.group_name = $1
+`
+
+func (s *DocumentStorage) GetGroupUsage(ctx context.Context, group string) (_ Usage, err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.GetGroupUsage")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "get_group_usage", time.Now(), &err)
+
+	var usage Usage
+	if err := s.db.GetContext(ctx, &usage, groupUsageQuery, group); err != nil {
+		return Usage{}, err
+	}
+	return usage, nil
+}
+
+// GetUsage без строки в user_usage возвращает нули.
+func (s *DocumentStorage) GetUsage(ctx context.Context, ownerID uuid.UUID) (_ Usage, err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.GetUsage")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "get_usage", time.Now(), &err)
+
+	var usage Usage
+	query := `SELECT bytes, documents FROM user_usage WHERE user_id = $1`
+	if err := s.db.GetContext(ctx, &usage, query, ownerID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Usage{}, err
+	}
+	return usage, nil
+}
+
+func (s *DocumentStorage) ListExpired(ctx context.Context, now time.Time, limit int) (_ []Document, err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.ListExpired")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "list_expired", time.Now(), &err)
+
+	var docs []Document
+	query := `SELECT * FROM documents WHERE expires_at <= $1 AND NOT legal_hold ORDER BY expires_at LIMIT $2`
+	if err := s.db.SelectContext(ctx, &docs, query, now, limit); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (s *DocumentStorage) SetLegalHold(ctx context.Context, id uuid.UUID, hold bool) (err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.SetLegalHold")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "set_legal_hold", time.Now(), &err)
+
+	return s.execOne(ctx, withEvent(EventUpdated, `UPDATE documents SET legal_hold = $2 WHERE id = $1`), id, hold)
+}
+
+// MoveToTrash возвращает ErrDocumentNotFound и для документа под удержанием.
+func (s *DocumentStorage) MoveToTrash(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.MoveToTrash")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "move_to_trash", time.Now(), &err)
+
+	query := `UPDATE documents SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL AND NOT legal_hold`
+	return s.execOne(ctx, withEvent(EventDeleted, query), id)
+}
+
+func (s *DocumentStorage) Restore(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.Restore")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "restore", time.Now(), &err)
+
+	return s.execOne(ctx, withEvent(EventRestored, `UPDATE documents SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`), id)
+}
+
+func (s *DocumentStorage) ListTrash(ctx context.Context, login string, limit int) (_ []Document, err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.ListTrash")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "list_trash", time.Now(), &err)
+
+	query := `SELECT * FROM documents WHERE deleted_at IS NOT NULL AND $1 = ANY(granted_to) ORDER BY deleted_at DESC`
+	args := []interface{}{login}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	var docs []Document
+	if err := s.db.SelectContext(ctx, &docs, query, args...); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (s *DocumentStorage) ListTrashedBefore(ctx context.Context, before time.Time, limit int) (_ []Document, err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.ListTrashedBefore")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "list_trashed", time.Now(), &err)
+
+	var docs []Document
+	query := `SELECT * FROM documents WHERE deleted_at <= $1 AND NOT legal_hold ORDER BY deleted_at LIMIT $2`
+	if err := s.db.SelectContext(ctx, &docs, query, before, limit); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (s *DocumentStorage) SetGrant(ctx context.Context, id uuid.UUID, grant []string) (err error) {
+	ctx, span := tracing.StartDB(ctx, "DocumentStorage.SetGrant")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("document", "set_grant", time.Now(), &err)
+
+	return s.execOne(ctx, withEvent(EventGranted, `UPDATE documents SET granted_to = $2 WHERE id = $1 AND deleted_at IS NULL`), id, pq.Array(grant))
+}
+
+// withEvent пишет событие на каждую строку UPDATE, не меняя число затронутых строк.
+func withEvent(eventType, update string) string {
+	return `
+		WITH changed AS (` + update + ` RETURNING id, name, granted_to)
+		INSERT INTO document_events (event_type, document_id, name, granted_to)
+		SELECT '` + eventType + `', id, name, granted_to FROM changed
+	`
+}
+
+func (s *DocumentStorage) execOne(ctx context.Context, query string, args ...interface{}) error {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrDocumentNotFound
+	}
+	return nil
+}
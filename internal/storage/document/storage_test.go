@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"document-server/internal/storage"
+	blobStorage "document-server/internal/storage/blob"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var errInjected = errors.New("injected failure")
+
+// TestCreateFaults: после любого сбоя загрузки не остается ни файлов, ни зафиксированной строки.
+func TestCreateFaults(t *testing.T) {
+	tests := []struct {
+		name string
+		// usage - использование владельца после вставки, groupUsage - его группы.
+		usage      int64
+		groupUsage int64
+		limits     Limits
+		// failRename подменяет переименование в beforeCommit ошибкой.
+		failRename bool
+		failCommit bool
+		wantErr    error
+	}{
+		{
+			name:       "before commit rename",
+			failRename: true,
+			wantErr:    errInjected,
+		},
+		{
+			name:    "quota rollback",
+			usage:   100,
+			limits:  Limits{User: Quota{MaxBytes: 10}},
+			wantErr: storage.ErrQuotaExceeded,
+		},
+		{
+			name:       "group quota rollback",
+			usage:      5,
+			groupUsage: 100,
+			limits:     Limits{User: Quota{MaxBytes: 10}, Group: "team", GroupQuota: Quota{MaxBytes: 50}},
+			wantErr:    storage.ErrGroupQuotaExceeded,
+		},
+		{
+			name:       "commit",
+			failCommit: true,
+			wantErr:    errInjected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			blobs := blobStorage.NewFileStore(dir, blobStorage.Options{})
+			staged, err := blobs.Stage(ctx, "doc", bytes.NewReader([]byte("hello")), false)
+			if err != nil {
+				t.Fatalf("Stage: %v", err)
+			}
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`INSERT INTO documents`).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(`INSERT INTO document_events`).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(`INSERT INTO user_usage`).
+				WillReturnRows(sqlmock.NewRows([]string{"bytes", "documents"}).AddRow(tt.usage, 1))
+			if tt.limits.Group != "" {
+				mock.ExpectQuery(`SELECT name FROM user_groups WHERE name = \$1 FOR UPDATE`).WithArgs(tt.limits.Group).
+					WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow(tt.limits.Group))
+				mock.ExpectQuery(`FROM user_usage JOIN users`).WithArgs(tt.limits.Group).
+					WillReturnRows(sqlmock.NewRows([]string{"bytes", "documents"}).AddRow(tt.groupUsage, 3))
+			}
+			if tt.failCommit {
+				mock.ExpectCommit().WillReturnError(errInjected)
+			} else {
+				mock.ExpectRollback()
+			}
+
+			renamed := false
+			beforeCommit := func() error {
+				renamed = true
+				if tt.failRename {
+					return errInjected
+				}
+				return staged.Commit()
+			}
+
+			doc := Document{
+				ID:         uuid.New(),
+				Name:       "doc",
+				IsFile:     true,
+				FilePath:   sql.NullString{String: staged.Path(), Valid: true},
+				StoredSize: sql.NullInt64{Int64: staged.StoredSize(), Valid: true},
+				OwnerID:    uuid.NullUUID{UUID: uuid.New(), Valid: true},
+			}
+			err = NewDocumentStorage(sqlx.NewDb(db, "postgres")).Create(ctx, doc, tt.limits, beforeCommit)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if (errors.Is(tt.wantErr, storage.ErrQuotaExceeded) || errors.Is(tt.wantErr, storage.ErrGroupQuotaExceeded)) && renamed {
+				t.Error("file was published although the quota check failed")
+			}
+			if err := staged.Abort(); err != nil {
+				t.Fatalf("Abort: %v", err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("transaction: %v", err)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				t.Errorf("file left behind: %s", e.Name())
+			}
+		})
+	}
+}
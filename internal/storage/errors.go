@@ -3,8 +3,13 @@ package storage
 import "errors"
 
 var (
-	ErrUserNotFound     = errors.New("user not found")
-	ErrDocumentNotFound = errors.New("document not found")
-	ErrUserExists       = errors.New("user with this login already exists")
-	ErrTokenNotFound    = errors.New("token not found")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrDocumentNotFound   = errors.New("document not found")
+	ErrUserExists         = errors.New("user with this login already exists")
+	ErrTokenNotFound      = errors.New("token not found")
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrQuotaExceeded      = errors.New("storage quota exceeded")
+	ErrGroupQuotaExceeded = errors.New("group storage quota exceeded")
+	ErrGroupNotFound      = errors.New("group not found")
+	ErrWebhookNotFound    = errors.New("webhook not found")
 )
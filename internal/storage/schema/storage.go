@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/tracing"
+	"errors"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrNoMigrations = errors.New("no migrations applied")
+
+// SchemaStorage отвечает на вопросы о самой базе: доступна ли она и какая версия схемы применена.
+type SchemaStorage struct {
+	db *sqlx.DB
+}
+
+func NewSchemaStorage(db *sqlx.DB) *SchemaStorage {
+	return &SchemaStorage{db: db}
+}
+
+func (s *SchemaStorage) Ping(ctx context.Context) (err error) {
+	ctx, span := tracing.StartDB(ctx, "SchemaStorage.Ping")
+	defer tracing.End(span, &err)
+
+	return s.db.PingContext(ctx)
+}
+
+func (s *SchemaStorage) PoolStats() sql.DBStats {
+	return s.db.Stats()
+}
+
+// Version читает таблицу schema_migrations, которую ведет golang-migrate.
+func (s *SchemaStorage) Version(ctx context.Context) (_ uint, _ bool, err error) {
+	ctx, span := tracing.StartDB(ctx, "SchemaStorage.Version")
+	defer tracing.End(span, &err)
+
+	var row struct {
+		Version uint `db:"version"`
+		Dirty   bool `db:"dirty"`
+	}
+	query := "SELECT version, dirty FROM schema_migrations LIMIT 1"
+	if err := s.db.GetContext(ctx, &row, query); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, ErrNoMigrations
+		}
+		return 0, false, err
+	}
+	return row.Version, row.Dirty, nil
+}
+
+// LatestMigrationVersion возвращает наибольший номер миграции вида 000001_name.up.sql.
+func LatestMigrationVersion(fsys fs.FS) (uint, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, uint(version))
+	}
+	if latest == 0 {
+		return 0, ErrNoMigrations
+	}
+	return latest, nil
+}
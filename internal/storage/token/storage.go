@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"document-server/internal/storage"
+	"document-server/internal/tracing"
 	"errors"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -17,17 +19,23 @@ func NewTokenStorage(db *sqlx.DB) *TokenStorage {
 	return &TokenStorage{db: db}
 }
 
-func (s *TokenStorage) Create(ctx context.Context, token UserToken) error {
+func (s *TokenStorage) Create(ctx context.Context, token UserToken) (err error) {
+	ctx, span := tracing.StartDB(ctx, "TokenStorage.Create")
+	defer tracing.End(span, &err)
+
 	query := `INSERT INTO user_tokens (token, user_id, expires_at)
               VALUES ($1, $2, $3)`
-	_, err := s.db.ExecContext(ctx, query, token.Token, token.UserID, token.ExpiresAt)
+	_, err = s.db.ExecContext(ctx, query, token.Token, token.UserID, token.ExpiresAt)
 	return err
 }
 
-func (s *TokenStorage) GetByToken(ctx context.Context, tokenValue string) (UserToken, error) {
+func (s *TokenStorage) GetByToken(ctx context.Context, tokenValue string) (_ UserToken, err error) {
+	ctx, span := tracing.StartDB(ctx, "TokenStorage.GetByToken")
+	defer tracing.End(span, &err)
+
 	var token UserToken
 	query := "SELECT token, user_id, expires_at FROM user_tokens WHERE token=$1"
-	err := s.db.GetContext(ctx, &token, query, tokenValue)
+	err = s.db.GetContext(ctx, &token, query, tokenValue)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return UserToken{}, storage.ErrTokenNotFound
@@ -37,8 +45,35 @@ func (s *TokenStorage) GetByToken(ctx context.Context, tokenValue string) (UserT
 	return token, nil
 }
 
-func (s *TokenStorage) Delete(ctx context.Context, tokenValue string) error {
+func (s *TokenStorage) Delete(ctx context.Context, tokenValue string) (err error) {
+	ctx, span := tracing.StartDB(ctx, "TokenStorage.Delete")
+	defer tracing.End(span, &err)
+
 	query := "DELETE FROM user_tokens WHERE token=$1"
-	_, err := s.db.ExecContext(ctx, query, tokenValue)
+	_, err = s.db.ExecContext(ctx, query, tokenValue)
 	return err
 }
+
+func (s *TokenStorage) DeleteExpired(ctx context.Context) (_ int64, err error) {
+	ctx, span := tracing.StartDB(ctx, "TokenStorage.DeleteExpired")
+	defer tracing.End(span, &err)
+
+	query := "DELETE FROM user_tokens WHERE expires_at < NOW()"
+	res, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *TokenStorage) DeleteByUserID(ctx context.Context, userID uuid.UUID) (_ int64, err error) {
+	ctx, span := tracing.StartDB(ctx, "TokenStorage.DeleteByUserID")
+	defer tracing.End(span, &err)
+
+	query := "DELETE FROM user_tokens WHERE user_id=$1"
+	res, err := s.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/storage"
+	"document-server/internal/tracing"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// SetGroup с NULL group убирает пользователя из группы.
+func (s *UserStorage) SetGroup(ctx context.Context, id uuid.UUID, group sql.NullString) (err error) {
+	ctx, span := tracing.StartDB(ctx, "UserStorage.SetGroup")
+	defer tracing.End(span, &err)
+
+	query := `
+		WITH created AS (
+			INSERT INTO user_groups (name) SELECT $2 WHERE $2 IS NOT NULL
+			ON CONFLICT (name) DO NOTHING
+		)
+		UPDATE users SET group_name = $2 WHERE id = $1
+	`
+	res, err := s.db.ExecContext(ctx, query, id, group)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *UserStorage) GetGroup(ctx context.Context, name string) (_ Group, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserStorage.GetGroup")
+	defer tracing.End(span, &err)
+
+	var group Group
+	query := "SELECT name, quota_bytes, quota_documents FROM user_groups WHERE name=$1"
+	if err := s.db.GetContext(ctx, &group, query, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Group{}, storage.ErrGroupNotFound
+		}
+		return Group{}, err
+	}
+	return group, nil
+}
+
+// SetGroupQuota: NULL возвращает значение из конфигурации.
+func (s *UserStorage) SetGroupQuota(ctx context.Context, name string, bytes, documents sql.NullInt64) (err error) {
+	ctx, span := tracing.StartDB(ctx, "UserStorage.SetGroupQuota")
+	defer tracing.End(span, &err)
+
+	query := `
+		INSERT INTO user_groups (name, quota_bytes, quota_documents) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET quota_bytes = EXCLUDED.quota_bytes, quota_documents = EXCLUDED.quota_documents
+	`
+	_, err = s.db.ExecContext(ctx, query, name, bytes, documents)
+	return err
+}
@@ -1,14 +1,28 @@
 package storage
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// User: квота NULL - значение из конфигурации, 0 - без ограничения.
 type User struct {
-	ID           uuid.UUID `db:"id"`
-	Login        string    `db:"login"`
-	PasswordHash string    `db:"password_hash"`
-	CreatedAt    time.Time `db:"created_at"`
+	ID              uuid.UUID      `db:"id"`
+	Login           string         `db:"login"`
+	PasswordHash    string         `db:"password_hash"`
+	CreatedAt       time.Time      `db:"created_at"`
+	ExternalIssuer  sql.NullString `db:"external_issuer"`
+	ExternalSubject sql.NullString `db:"external_subject"`
+	IsAdmin         bool           `db:"is_admin"`
+	QuotaBytes      sql.NullInt64  `db:"quota_bytes"`
+	QuotaDocuments  sql.NullInt64  `db:"quota_documents"`
+	Group           sql.NullString `db:"group_name"`
+}
+
+type Group struct {
+	Name           string        `db:"name"`
+	QuotaBytes     sql.NullInt64 `db:"quota_bytes"`
+	QuotaDocuments sql.NullInt64 `db:"quota_documents"`
 }
@@ -4,12 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"document-server/internal/storage"
+	"document-server/internal/tracing"
 	"errors"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
+const userColumns = "id, login, password_hash, created_at, external_issuer, external_subject, is_admin, quota_bytes, quota_documents, group_name"
+
 type UserStorage struct {
 	db *sqlx.DB
 }
@@ -18,19 +21,42 @@ func NewUserStorage(db *sqlx.DB) *UserStorage {
 	return &UserStorage{db: db}
 }
 
-func (s *UserStorage) Create(ctx context.Context, user User) error {
-	query := "INSERT INTO users (login, password_hash) VALUES ($1, $2)"
-	_, err := s.db.Exec(query, user.Login, user.PasswordHash)
+func (s *UserStorage) Create(ctx context.Context, user User) (err error) {
+	ctx, span := tracing.StartDB(ctx, "UserStorage.Create")
+	defer tracing.End(span, &err)
+
+	query := "INSERT INTO users (login, password_hash, external_issuer, external_subject, is_admin) VALUES ($1, $2, $3, $4, $5)"
+	_, err = s.db.ExecContext(ctx, query, user.Login, user.PasswordHash, user.ExternalIssuer, user.ExternalSubject, user.IsAdmin)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (s *UserStorage) GetUserByLogin(ctx context.Context, login string) (User, error) {
+func (s *UserStorage) GetUserByLogin(ctx context.Context, login string) (_ User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserStorage.GetUserByLogin")
+	defer tracing.End(span, &err)
+
+	var user User
+	query := "SELECT " + userColumns + " FROM users WHERE login=$1"
+	err = s.db.GetContext(ctx, &user, query, login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, storage.ErrUserNotFound
+		}
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *UserStorage) GetUserByID(ctx context.Context, uuid uuid.UUID) (_ User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserStorage.GetUserByID")
+	defer tracing.End(span, &err)
+
 	var user User
-	query := "SELECT id, login, password_hash, created_at FROM users WHERE login=$1"
-	err := s.db.GetContext(ctx, &user, query, login)
+	query := "SELECT " + userColumns + " FROM users WHERE id=$1"
+	err = s.db.GetContext(ctx, &user, query, uuid)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return User{}, storage.ErrUserNotFound
@@ -40,10 +66,14 @@ func (s *UserStorage) GetUserByLogin(ctx context.Context, login string) (User, e
 
 	return user, nil
 }
-func (s *UserStorage) GetUserByID(ctx context.Context, uuid uuid.UUID) (User, error) {
+
+func (s *UserStorage) GetUserByExternalID(ctx context.Context, issuer, subject string) (_ User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserStorage.GetUserByExternalID")
+	defer tracing.End(span, &err)
+
 	var user User
-	query := "SELECT id, login, password_hash, created_at FROM users WHERE id=$1"
-	err := s.db.GetContext(ctx, &user, query, uuid)
+	query := "SELECT " + userColumns + " FROM users WHERE external_issuer=$1 AND external_subject=$2"
+	err = s.db.GetContext(ctx, &user, query, issuer, subject)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return User{}, storage.ErrUserNotFound
@@ -53,3 +83,43 @@ func (s *UserStorage) GetUserByID(ctx context.Context, uuid uuid.UUID) (User, er
 
 	return user, nil
 }
+
+func (s *UserStorage) List(ctx context.Context) (_ []User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserStorage.List")
+	defer tracing.End(span, &err)
+
+	var users []User
+	query := "SELECT " + userColumns + " FROM users ORDER BY login ASC"
+	if err := s.db.SelectContext(ctx, &users, query); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// DeleteByID удаляет пользователя; токены и API ключи удаляются каскадно.
+func (s *UserStorage) DeleteByID(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.StartDB(ctx, "UserStorage.DeleteByID")
+	defer tracing.End(span, &err)
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM users WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrUserNotFound
+	}
+	return nil
+}
+
+// SetQuota задает квоты пользователя; NULL возвращает значение из конфигурации.
+func (s *UserStorage) SetQuota(ctx context.Context, id uuid.UUID, bytes, documents sql.NullInt64) (err error) {
+	ctx, span := tracing.StartDB(ctx, "UserStorage.SetQuota")
+	defer tracing.End(span, &err)
+
+	_, err = s.db.ExecContext(ctx, "UPDATE users SET quota_bytes=$2, quota_documents=$3 WHERE id=$1", id, bytes, documents)
+	return err
+}
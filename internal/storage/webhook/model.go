@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+type Subscription struct {
+	ID        uuid.UUID      `db:"id"`
+	UserID    uuid.UUID      `db:"user_id"`
+	URL       string         `db:"url"`
+	Events    pq.StringArray `db:"events"`
+	Secret    string         `db:"secret"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+type Delivery struct {
+	ID             int64          `db:"id"`
+	SubscriptionID uuid.UUID      `db:"subscription_id"`
+	EventID        int64          `db:"event_id"`
+	Status         string         `db:"status"`
+	Attempts       int            `db:"attempts"`
+	NextAttemptAt  time.Time      `db:"next_attempt_at"`
+	LastStatus     sql.NullInt32  `db:"last_status"`
+	LastError      sql.NullString `db:"last_error"`
+	DeliveredAt    sql.NullTime   `db:"delivered_at"`
+	CreatedAt      time.Time      `db:"created_at"`
+	EventType      string         `db:"event_type"`
+	DocumentID     uuid.UUID      `db:"document_id"`
+}
+
+// Job - доставка, взятая в работу, со всем, что нужно для запроса.
+type Job struct {
+	ID         int64          `db:"id"`
+	Attempts   int            `db:"attempts"`
+	URL        string         `db:"url"`
+	Secret     string         `db:"secret"`
+	EventID    int64          `db:"event_id"`
+	EventType  string         `db:"event_type"`
+	DocumentID uuid.UUID      `db:"document_id"`
+	Name       string         `db:"name"`
+	GrantedTo  pq.StringArray `db:"granted_to"`
+	OccurredAt time.Time      `db:"occurred_at"`
+}
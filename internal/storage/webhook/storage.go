@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"document-server/internal/metrics"
+	"document-server/internal/storage"
+	"document-server/internal/tracing"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type WebhookStorage struct {
+	db *sqlx.DB
+}
+
+func NewWebhookStorage(db *sqlx.DB) *WebhookStorage {
+	return &WebhookStorage{db: db}
+}
+
+func (s *WebhookStorage) Create(ctx context.Context, sub Subscription) (err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.Create")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "create", time.Now(), &err)
+
+	query := `
+		INSERT INTO webhook_subscriptions (id, user_id, url, events, secret)
+		VALUES (:id, :user_id, :url, :events, :secret)
+	`
+	_, err = s.db.NamedExecContext(ctx, query, sub)
+	return err
+}
+
+func (s *WebhookStorage) Get(ctx context.Context, id uuid.UUID) (_ Subscription, err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.Get")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "get", time.Now(), &err)
+
+	var sub Subscription
+	if err := s.db.GetContext(ctx, &sub, `SELECT * FROM webhook_subscriptions WHERE id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Subscription{}, storage.ErrWebhookNotFound
+		}
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+func (s *WebhookStorage) ListByUserID(ctx context.Context, userID uuid.UUID) (_ []Subscription, err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.ListByUserID")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "list", time.Now(), &err)
+
+	var subs []Subscription
+	query := `SELECT * FROM webhook_subscriptions WHERE user_id = $1 ORDER BY created_at DESC`
+	if err := s.db.SelectContext(ctx, &subs, query, userID); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// Delete удаляет подписку пользователя вместе с ее доставками.
+func (s *WebhookStorage) Delete(ctx context.Context, id, userID uuid.UUID) (err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.Delete")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "delete", time.Now(), &err)
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Dispatch создает доставки только подписчикам с доступом к документу.
+func (s *WebhookStorage) Dispatch(ctx context.Context, limit int) (_ int, err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.Dispatch")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "dispatch", time.Now(), &err)
+
+	query := `
+		WITH events AS (
+			SELECT id, event_type, granted_to FROM document_events
+			WHERE dispatched_at IS NULL ORDER BY id LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), fanout AS (
+			INSERT INTO webhook_deliveries (subscription_id, event_id)
+			SELECT s.id, e.id FROM events e
+			JOIN webhook_subscriptions s ON e.event_type = ANY(s.events)
+			JOIN users u ON u.id = s.user_id AND u.login = ANY(e.granted_to)
+		)
+		UPDATE document_events SET dispatched_at = NOW()
+		FROM events WHERE document_events.id = events.id
+	`
+	res, err := s.db.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// DeleteEvents не трогает события с ожидающими доставками.
+func (s *WebhookStorage) DeleteEvents(ctx context.Context, before time.Time, undispatched bool, limit int) (_ int, err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.DeleteEvents")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "delete_events", time.Now(), &err)
+
+	query := `
+		DELETE FROM document_events WHERE id IN (
+			SELECT e.id FROM document_events e
+			WHERE e.created_at < $1 AND (e.dispatched_at IS NOT NULL OR $2)
+			AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.event_id = e.id AND d.status = 'pending')
+			ORDER BY e.id LIMIT $3
+		)
+	`
+	res, err := s.db.ExecContext(ctx, query, before, undispatched, limit)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Claim откладывает следующую попытку на lease на случай падения процесса.
+func (s *WebhookStorage) Claim(ctx context.Context, limit int, lease time.Duration) (_ []Job, err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.Claim")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "claim", time.Now(), &err)
+
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries SET next_attempt_at = NOW() + $2::float8 * INTERVAL '1 second'
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, attempts, subscription_id, event_id
+		)
+		SELECT c.id, c.attempts, s.url, s.secret, e.id AS event_id, e.event_type, e.document_id, e.name, e.granted_to, e.created_at AS occurred_at
+		FROM claimed c
+		JOIN webhook_subscriptions s ON s.id = c.subscription_id
+		JOIN document_events e ON e.id = c.event_id
+		ORDER BY c.id
+	`
+	var jobs []Job
+	if err := s.db.SelectContext(ctx, &jobs, query, limit, lease.Seconds()); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Delivered отмечает успешную доставку.
+func (s *WebhookStorage) Delivered(ctx context.Context, id int64, status int) (err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.Delivered")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "delivered", time.Now(), &err)
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, last_status = $2, last_error = NULL, delivered_at = NOW()
+		WHERE id = $1
+	`
+	_, err = s.db.ExecContext(ctx, query, id, status)
+	return err
+}
+
+func (s *WebhookStorage) Failed(ctx context.Context, id int64, status sql.NullInt32, errText string, next time.Time, dead bool) (err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.Failed")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "failed", time.Now(), &err)
+
+	newStatus := StatusPending
+	if dead {
+		newStatus = StatusDead
+	}
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = attempts + 1, last_status = $3, last_error = $4, next_attempt_at = $5
+		WHERE id = $1
+	`
+	_, err = s.db.ExecContext(ctx, query, id, newStatus, status, errText, next)
+	return err
+}
+
+// ListDeliveries возвращает последние доставки подписки; пустой status - любые.
+func (s *WebhookStorage) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) (_ []Delivery, err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.ListDeliveries")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "list_deliveries", time.Now(), &err)
+
+	query := `
+		SELECT d.*, e.event_type, e.document_id
+		FROM webhook_deliveries d JOIN document_events e ON e.id = d.event_id
+		WHERE d.subscription_id = $1 AND ($2 = '' OR d.status = $2)
+		ORDER BY d.id DESC LIMIT $3
+	`
+	var deliveries []Delivery
+	if err := s.db.SelectContext(ctx, &deliveries, query, subscriptionID, status, limit); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Redeliver ставит доставку подписки в очередь заново со сброшенным числом попыток.
+func (s *WebhookStorage) Redeliver(ctx context.Context, id int64, subscriptionID uuid.UUID) (err error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookStorage.Redeliver")
+	defer tracing.End(span, &err)
+
+	defer metrics.ObserveStorageOperation("webhook", "redeliver", time.Now(), &err)
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), last_error = NULL
+		WHERE id = $1 AND subscription_id = $2
+	`
+	res, err := s.db.ExecContext(ctx, query, id, subscriptionID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrWebhookNotFound
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"document-server/internal/config"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func exportedSpans(t *testing.T, ratio float64, run func()) []map[string]any {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := Setup(context.Background(), config.TracingConfig{
+		Exporter:    "file",
+		FilePath:    path,
+		ServiceName: "test",
+		SampleRatio: ratio,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spans []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var span map[string]any
+		if err := dec.Decode(&span); err != nil {
+			t.Fatalf("decode span: %v", err)
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+func TestFileExporter(t *testing.T) {
+	spans := exportedSpans(t, 1, func() {
+		ctx, parent := Start(context.Background(), "parent")
+		_, child := StartDB(ctx, "DocumentStorage.GetByID")
+		err := errors.New("no rows")
+		End(child, &err)
+		parent.End()
+	})
+
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+	child, parent := spans[0], spans[1]
+	if child["Name"] != "DocumentStorage.GetByID" || parent["Name"] != "parent" {
+		t.Fatalf("span names %v, %v", child["Name"], parent["Name"])
+	}
+	childParent := child["Parent"].(map[string]any)
+	parentContext := parent["SpanContext"].(map[string]any)
+	if childParent["SpanID"] != parentContext["SpanID"] || childParent["TraceID"] != parentContext["TraceID"] {
+		t.Error("database span is not a child of the parent span")
+	}
+	if status := child["Status"].(map[string]any); status["Code"] != "Error" || status["Description"] != "no rows" {
+		t.Errorf("database span status = %v, want the error", status)
+	}
+	if !strings.Contains(string(mustJSON(t, child["Attributes"])), "postgresql") {
+		t.Errorf("database span attributes = %v, want db.system postgresql", child["Attributes"])
+	}
+}
+
+func TestSampleRatioZeroRecordsNothing(t *testing.T) {
+	spans := exportedSpans(t, 0, func() {
+		for range 10 {
+			_, span := Start(context.Background(), "request")
+			span.End()
+		}
+	})
+	if len(spans) != 0 {
+		t.Errorf("exported %d spans with sampleRatio 0", len(spans))
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -1 +1,3 @@
+DROP INDEX IF EXISTS idx_users_login;
+
 DROP TABLE IF EXISTS users;
\ No newline at end of file
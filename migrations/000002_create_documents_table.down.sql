@@ -1 +1,3 @@
+DROP INDEX IF EXISTS idx_documents_name_created_at;
+
 DROP TABLE IF EXISTS documents;
\ No newline at end of file
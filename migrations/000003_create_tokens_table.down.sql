@@ -1 +1,3 @@
+DROP INDEX IF EXISTS idx_user_tokens_expires_at;
+
 DROP TABLE IF EXISTS user_tokens;
\ No newline at end of file
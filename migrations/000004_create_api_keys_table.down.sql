@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_api_keys_user_id;
+
+DROP TABLE IF EXISTS api_keys;
\ No newline at end of file
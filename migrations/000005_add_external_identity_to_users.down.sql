@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_external_identity;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS external_issuer,
+    DROP COLUMN IF EXISTS external_subject;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN external_issuer TEXT,
+    ADD COLUMN external_subject TEXT;
+
+CREATE UNIQUE INDEX idx_users_external_identity ON users (external_issuer, external_subject) WHERE external_subject IS NOT NULL;
\ No newline at end of file
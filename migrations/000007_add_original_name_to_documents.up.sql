@@ -0,0 +1,2 @@
+ALTER TABLE documents
+    ADD COLUMN original_name TEXT;
\ No newline at end of file
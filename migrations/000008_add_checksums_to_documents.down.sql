@@ -0,0 +1,3 @@
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS sha256,
+    DROP COLUMN IF EXISTS md5;
\ No newline at end of file
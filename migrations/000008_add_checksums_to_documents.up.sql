@@ -0,0 +1,3 @@
+ALTER TABLE documents
+    ADD COLUMN sha256 CHAR(64),
+    ADD COLUMN md5 CHAR(32);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS encryption_key_id,
+    DROP COLUMN IF EXISTS wrapped_key;
\ No newline at end of file
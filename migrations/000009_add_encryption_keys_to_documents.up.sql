@@ -0,0 +1,3 @@
+ALTER TABLE documents
+    ADD COLUMN encryption_key_id TEXT,
+    ADD COLUMN wrapped_key BYTEA;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS size,
+    DROP COLUMN IF EXISTS stored_size,
+    DROP COLUMN IF EXISTS content_encoding;
@@ -0,0 +1,6 @@
+ALTER TABLE documents
+    ADD COLUMN size BIGINT,
+    ADD COLUMN stored_size BIGINT,
+    ADD COLUMN content_encoding TEXT;
+
+UPDATE documents SET size = octet_length(json_content::text) WHERE json_content IS NOT NULL;
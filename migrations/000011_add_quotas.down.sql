@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS user_usage;
+
+DROP INDEX IF EXISTS idx_documents_owner_id;
+
+ALTER TABLE documents DROP COLUMN IF EXISTS owner_id;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS quota_bytes,
+    DROP COLUMN IF EXISTS quota_documents;
\ No newline at end of file
@@ -0,0 +1,28 @@
+ALTER TABLE users
+    ADD COLUMN quota_bytes BIGINT,
+    ADD COLUMN quota_documents BIGINT;
+
+ALTER TABLE documents ADD COLUMN owner_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_documents_owner_id ON documents (owner_id);
+
+CREATE TABLE user_usage (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    documents BIGINT NOT NULL DEFAULT 0
+);
+
+-- Владелец старых документов не записан. Загрузивший всегда попадал в granted_to,
+-- поэтому документ с единственным получателем доступа принадлежит ему; у
+-- документов с несколькими получателями владельца не угадать, они не учитываются.
+UPDATE documents d SET owner_id = u.id
+FROM users u
+WHERE d.owner_id IS NULL AND cardinality(d.granted_to) = 1 AND u.login = d.granted_to[1];
+
+-- Размеры файлов, загруженных до 000010, не записаны и считаются здесь нулем:
+-- после миграции их заполняет docsctl backfill-sizes.
+INSERT INTO user_usage (user_id, bytes, documents)
+SELECT owner_id, SUM(COALESCE(stored_size, size, 0)), COUNT(*)
+FROM documents
+WHERE owner_id IS NOT NULL
+GROUP BY owner_id;
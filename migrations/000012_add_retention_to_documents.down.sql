@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_documents_expires_at;
+
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS legal_hold;
\ No newline at end of file
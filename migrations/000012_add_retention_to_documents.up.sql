@@ -0,0 +1,5 @@
+ALTER TABLE documents
+    ADD COLUMN expires_at TIMESTAMPTZ,
+    ADD COLUMN legal_hold BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_documents_expires_at ON documents (expires_at) WHERE expires_at IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE documents
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_documents_deleted_at ON documents (deleted_at) WHERE deleted_at IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,28 @@
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    action TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    actor TEXT,
+    target TEXT,
+    ip TEXT,
+    user_agent TEXT,
+    request_id TEXT,
+    detail TEXT
+);
+
+CREATE INDEX idx_audit_events_occurred_at ON audit_events (occurred_at);
+CREATE INDEX idx_audit_events_actor ON audit_events (actor, occurred_at);
+CREATE INDEX idx_audit_events_target ON audit_events (target, occurred_at);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
\ No newline at end of file
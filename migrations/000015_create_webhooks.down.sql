@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_event_id;
+DROP INDEX IF EXISTS idx_webhook_deliveries_subscription_id;
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+DROP TABLE IF EXISTS webhook_deliveries;
+
+DROP INDEX IF EXISTS idx_webhook_subscriptions_user_id;
+DROP TABLE IF EXISTS webhook_subscriptions;
+
+DROP INDEX IF EXISTS idx_document_events_undispatched;
+DROP TABLE IF EXISTS document_events;
\ No newline at end of file
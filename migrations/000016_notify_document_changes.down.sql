@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS documents_notify_change ON documents;
+DROP FUNCTION IF EXISTS notify_document_change();
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE FUNCTION notify_document_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('document_changes', OLD.id::text);
+    ELSE
+        PERFORM pg_notify('document_changes', NEW.id::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER documents_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON documents
+    FOR EACH ROW EXECUTE FUNCTION notify_document_change();
\ No newline at end of file
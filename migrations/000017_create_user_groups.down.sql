@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_group_name;
+
+ALTER TABLE users DROP COLUMN IF EXISTS group_name;
+
+DROP TABLE IF EXISTS user_groups;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE user_groups (
+    name TEXT PRIMARY KEY,
+    quota_bytes BIGINT,
+    quota_documents BIGINT
+);
+
+ALTER TABLE users ADD COLUMN group_name TEXT REFERENCES user_groups(name) ON DELETE SET NULL;
+
+CREATE INDEX idx_users_group_name ON users (group_name);
\ No newline at end of file